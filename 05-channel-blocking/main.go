@@ -1,7 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
  
@@ -11,50 +16,118 @@ import (
 // this example is in contrast to the 'select-statement' example
 
 // https://golang.org/ref/spec#Select_statements
+// https://golang.org/pkg/os/signal/
 
 // "04:05" is a predefined 'Time.Format' layout for hour:minute
 
-// send a 'fast' 1 sec delayed message
-func fastChannelSender(channel chan string) {
+// the senders never stop on their own, so the program is stopped with ^C ('SIGINT') or 'kill' ('SIGTERM')
+// instead of being killed, 'main' catches the signal and shuts down in one of 2 modes ('-shutdown'):
+// "drain": the senders stop sending, the messages they already started sending are received within '-grace'
+// "abort": stop right away and report the messages that were sent but never received
+// a second signal during a drain aborts it, each mode exits with its own code
+const (
+	exitDrained = 3
+	exitAborted = 4
+)
+
+// 'sent' and 'received' count messages so an abort can report what was left
+var sent, received atomic.Int64
+
+// send a 'fast' 1 sec delayed message until 'stop' is closed
+// the channel is closed once the sender stops so the receiver knows no more messages will come
+func fastChannelSender(channel chan string, stop chan struct{}) {
+	defer close(channel)
 	for {
+		sent.Add(1)
 		channel <- time.Now().Format("04:05")
-		time.Sleep(1 * time.Second)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-stop:
+			return
+		}
 	}
 }
 
-// send a 'slow' 6 sec delayed message
-func slowChannelSender(channel chan string) {
+// send a 'slow' 6 sec delayed message until 'stop' is closed
+func slowChannelSender(channel chan string, stop chan struct{}) {
+	defer close(channel)
 	for {
+		sent.Add(1)
 		channel <- time.Now().Format("04:05")
-		time.Sleep(6 * time.Second)
+		select {
+		case <-time.After(6 * time.Second):
+		case <-stop:
+			return
+		}
 	}
 }
 
 
 func main() {
 
+	shutdown := flag.String("shutdown", "drain", "on SIGINT/SIGTERM: drain or abort")
+	grace := flag.Duration("grace", 10*time.Second, "drain grace period before the program aborts")
+	flag.Parse()
+
+	// 'signal.Notify' delivers SIGINT/SIGTERM to the 'signals' channel instead of terminating the program
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	// create 2 channels
 	fastChannel := make(chan string)
 	slowChannel := make(chan string)
+	stop := make(chan struct{})
 
 	// goroutine function calls to the sending functions
-	go fastChannelSender(fastChannel)
-	go slowChannelSender(slowChannel)
+	go fastChannelSender(fastChannel, stop)
+	go slowChannelSender(slowChannel, stop)
 
-	// loop with receiver for each channel
-	for {
-		slowChannelMessage, open := <- slowChannel
-		fastChannelMessage, open := <- fastChannel
-		if !open {
-			break
+	// loop with receiver for each channel (in a goroutine so 'main' can wait for a signal)
+	// the loop ends once both senders stopped and closed their channel
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			slowChannelMessage, slowOpen := <- slowChannel
+			fastChannelMessage, fastOpen := <- fastChannel
+			if !slowOpen && !fastOpen {
+				break
+			}
+			fmt.Println(">>>>>>>>>> Received Messages at:", time.Now().Format("04:05"), "<<<<<<<<<<<<<")
+			if fastOpen {
+				received.Add(1)
+				fmt.Printf("%v --fastChannelMessage Received! \n", fastChannelMessage)
+			}
+			if slowOpen {
+				received.Add(1)
+				fmt.Printf("%v 	--slowChannelMessage Received! \n", slowChannelMessage)
+			}
 		}
-		fmt.Println(">>>>>>>>>> Received Messages at:", time.Now().Format("04:05"), "<<<<<<<<<<<<<")
-		fmt.Printf("%v --fastChannelMessage Received! \n", fastChannelMessage)
-		fmt.Printf("%v 	--slowChannelMessage Received! \n", slowChannelMessage)
+	}()
+
+	// block until a signal
+	sig := <- signals
+
+	if *shutdown == "abort" {
+		fmt.Printf("%v: aborting, %v message(s) sent but never received \n", sig, sent.Load()-received.Load())
+		os.Exit(exitAborted)
 	}
 
-	// block forever
-	select {}
+	// stop the senders, the blocked sends are still received
+	fmt.Printf("%v: draining, %v message(s) pending (grace period: %v, ^C again to abort) \n", sig, sent.Load()-received.Load(), *grace)
+	close(stop)
+
+	select {
+	case <- done:
+		fmt.Printf("drained, %v message(s) received \n", received.Load())
+		os.Exit(exitDrained)
+	case <- time.After(*grace):
+		fmt.Printf("grace period of %v is over, aborting \n", *grace)
+	case sig = <- signals:
+		fmt.Printf("%v: aborting the drain \n", sig)
+	}
+	fmt.Printf("%v message(s) sent but never received \n", sent.Load()-received.Load())
+	os.Exit(exitAborted)
 }
 
 // example with 'fastChannelSender()' sleeping 1 sec & 'slowChannelSender()' sleeping 6 secs
@@ -131,3 +204,28 @@ func main() {
 //	19:25 --fastChannelMessage Received! 
 //	19:14 	--slowChannelMessage Received! 
 //	^Csignal: interrupt
+
+// example stopped with ^C after 8 secs ('-shutdown drain', the default)
+// the fast sender was blocked sending its 31:37 message (the receiver waits on the slow channel), it is still received
+//
+//	% go run main.go
+//	>>>>>>>>>> Received Messages at: 31:30 <<<<<<<<<<<<<
+//	31:30 --fastChannelMessage Received! 
+//	31:30 	--slowChannelMessage Received! 
+//	>>>>>>>>>> Received Messages at: 31:36 <<<<<<<<<<<<<
+//	31:31 --fastChannelMessage Received! 
+//	31:36 	--slowChannelMessage Received! 
+//	^Cinterrupt: draining, 1 message(s) pending (grace period: 10s, ^C again to abort) 
+//	>>>>>>>>>> Received Messages at: 31:39 <<<<<<<<<<<<<
+//	31:37 --fastChannelMessage Received! 
+//	drained, 5 message(s) received 
+//	exit status 3
+
+// example with '-shutdown abort' stopped with 'kill' ('SIGTERM') after 2 secs
+//
+//	% go run main.go -shutdown abort
+//	>>>>>>>>>> Received Messages at: 31:39 <<<<<<<<<<<<<
+//	31:39 --fastChannelMessage Received! 
+//	31:39 	--slowChannelMessage Received! 
+//	terminated: aborting, 1 message(s) sent but never received 
+//	exit status 4
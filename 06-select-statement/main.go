@@ -1,7 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -18,38 +23,77 @@ import (
 
 // https://golang.org/ref/spec#Select_statements
 // https://golang.org/pkg/time/#After
+// https://golang.org/pkg/os/signal/
 
 // "04:05" is a predefined 'Time.Format' layout for hour:minute
 
-// send a 'fast' 1 sec delayed message
-func fastChannelSender(channel chan string) {
+// a signal (^C is 'SIGINT', 'kill' sends 'SIGTERM') is just one more 'case' of the 'select' statement
+// "drain" ('-shutdown'): the senders stop, the loop keeps receiving until both channels are closed or '-grace' is over
+// "abort": the loop stops right away and reports the messages that were sent but never received
+// a second signal during a drain aborts it, each mode exits with its own code
+const (
+	exitDrained = 3
+	exitAborted = 4
+)
+
+// 'sent' counts messages so an abort can report what was left
+var sent atomic.Int64
+
+// send a 'fast' 1 sec delayed message until 'stop' is closed, then close the channel
+func fastChannelSender(channel chan string, stop chan struct{}) {
+	defer close(channel)
 	for {
+		sent.Add(1)
 		channel <- time.Now().Format("04:05")
-		time.Sleep(1 * time.Second)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-stop:
+			return
+		}
 	}
 }
 
-// send a 'slow' 6 sec delayed message
-func slowChannelSender(channel chan string) {
+// send a 'slow' 6 sec delayed message until 'stop' is closed, then close the channel
+func slowChannelSender(channel chan string, stop chan struct{}) {
+	defer close(channel)
 	for {
+		sent.Add(1)
 		channel <- time.Now().Format("04:05")
-		time.Sleep(6 * time.Second)
+		select {
+		case <-time.After(6 * time.Second):
+		case <-stop:
+			return
+		}
 	}
 }
 
 
 func main() {
 
+	shutdown := flag.String("shutdown", "drain", "on SIGINT/SIGTERM: drain or abort")
+	grace := flag.Duration("grace", 10*time.Second, "drain grace period before the program aborts")
+	flag.Parse()
+
+	// 'signal.Notify' delivers SIGINT/SIGTERM to the 'signals' channel instead of terminating the program
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	// create a 26 second counter channel
 	timeoutChannel := time.After(26 * time.Second)
 
 	// create 2 empty channels
 	fastChannel := make(chan string)
 	slowChannel := make(chan string)
+	stop := make(chan struct{})
 
 	// goroutine function calls to the sending functions
-	go fastChannelSender(fastChannel)
-	go slowChannelSender(slowChannel)
+	go fastChannelSender(fastChannel, stop)
+	go slowChannelSender(slowChannel, stop)
+
+	// 'graceChannel' stays nil until a drain starts, a 'nil' channel is never selected
+	var graceChannel <-chan time.Time
+	var received int64
+	exitCode := 0
 
 	// a labeled break -a label to target the 'breaking-out' of the loop
 	LabeledStatement:
@@ -60,12 +104,50 @@ func main() {
 			fmt.Println("================================================")
 			fmt.Printf("time duration: '%T' >>>>> has elapsed at: %v \n", timeoutChannel, done.Format("04:05"))
 			break LabeledStatement
-		case slowChannelMessage := <- slowChannel:
+		case sig := <- signals:
+			if *shutdown == "abort" || graceChannel != nil {
+				fmt.Printf("%v: aborting \n", sig)
+				exitCode = exitAborted
+				break LabeledStatement
+			}
+			// stop the senders and the 26 second counter, keep receiving what was already sent
+			fmt.Printf("%v: draining, %v message(s) pending (grace period: %v, ^C again to abort) \n", sig, sent.Load()-received, *grace)
+			close(stop)
+			timeoutChannel = nil
+			graceChannel = time.After(*grace)
+		case <- graceChannel:
+			fmt.Printf("grace period of %v is over, aborting \n", *grace)
+			exitCode = exitAborted
+			break LabeledStatement
+		case slowChannelMessage, open := <- slowChannel:
+			if !open {
+				// the sender stopped, stop selecting its channel
+				slowChannel = nil
+				break
+			}
+			received++
 			fmt.Printf("%v 	--slowChannelMessage Received! \n", slowChannelMessage)
-		case fastChannelMessage := <- fastChannel:
+		case fastChannelMessage, open := <- fastChannel:
+			if !open {
+				fastChannel = nil
+				break
+			}
+			received++
 			fmt.Printf("%v --fastChannelMessage Received! \n", fastChannelMessage)
 		}
+
+		// both senders stopped and everything they sent was received
+		if slowChannel == nil && fastChannel == nil {
+			fmt.Printf("drained, %v message(s) received \n", received)
+			exitCode = exitDrained
+			break
+		}
+	}
+
+	if exitCode == exitAborted {
+		fmt.Printf("%v message(s) sent but never received \n", sent.Load()-received)
 	}
+	os.Exit(exitCode)
 }
 
 // example with 'fastChannelSender()' sleeping 1 sec & 'slowChannelSender()' sleeping 6 secs
@@ -104,3 +186,27 @@ func main() {
 //	16:47 --fastChannelMessage Received! 
 //	================================================
 //	time duration: '<-chan time.Time' >>>>> has elapsed at: 16:48
+
+// example stopped with ^C after 3 secs ('-shutdown drain', the default)
+// the 'select' statement never blocks on one channel so nothing is pending, both senders stop and close their channel
+//
+//	% go run main.go
+//	32:02 	--slowChannelMessage Received! 
+//	32:02 --fastChannelMessage Received! 
+//	32:03 --fastChannelMessage Received! 
+//	32:04 --fastChannelMessage Received! 
+//	32:05 --fastChannelMessage Received! 
+//	^Cinterrupt: draining, 0 message(s) pending (grace period: 10s, ^C again to abort) 
+//	drained, 5 message(s) received 
+//	exit status 3
+
+// example with '-shutdown abort' stopped with 'kill' ('SIGTERM') after 2 secs
+//
+//	% go run main.go -shutdown abort
+//	32:06 	--slowChannelMessage Received! 
+//	32:06 --fastChannelMessage Received! 
+//	32:07 --fastChannelMessage Received! 
+//	32:08 --fastChannelMessage Received! 
+//	terminated: aborting 
+//	0 message(s) sent but never received 
+//	exit status 4
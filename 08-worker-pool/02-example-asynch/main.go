@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
 	"time"
 	"sync"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/internal/goroutines"
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/internal/semaphore"
 )
 
 // example demonstrates asychronously processing data (no worker pool)
 // example demonstrates processing 1000 simulated API calls each of which take 1/10th of a sec to complete
 // this is in contrast to '01-example-synch' and '03-example-worker-pool'
 
+// one goroutine per API call is unbounded: 1M calls start 1M goroutines at once and can exhaust memory
+// 'workBounded()' keeps the "goroutine per task" style but a weighted semaphore caps the goroutines in flight
+
 type apiDataType struct {
 	id int
 }
@@ -30,6 +40,9 @@ func work(allApiCalls []apiDataType, numApiCalls int) {
 
 	startTime := time.Now()
 
+	// sample 'runtime.NumGoroutine()' to display the peak goroutine count
+	sampler := goroutines.Sample(time.Millisecond)
+
 	// the object here is to load (pre-load) all API calls to measure elapsed time
 	// since each API call is now its own goroutine, using 'WaitGroup' is needed
 	// 'WaitGroup' will wait for all goroutines to finish (Promise.all())
@@ -82,26 +95,250 @@ func work(allApiCalls []apiDataType, numApiCalls int) {
 	timeSinceStart := time.Since(startTime)
 
 	fmt.Printf("total API processing time: %v \n", timeSinceStart)
+	fmt.Printf("peak goroutines: %v \n", sampler.Stop())
+}
+
+// the semaphore is released when the goroutine finishes its API call
+func fetchBounded(data apiDataType, inFlight *semaphore.Semaphore, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer inFlight.Release(1)
+	apiRequest(data)
+}
+
+// 'workBounded' is 'work()' with at most 'maxInFlight' fetch goroutines at once
+// the reading goroutine acquires 1 unit of the weighted semaphore before each 'go fetchBounded()'
+// and blocks while 'maxInFlight' units are held, so calls are spread over time instead of all started at once
+func workBounded(allApiCalls []apiDataType, maxInFlight int64) {
+	fmt.Printf("start requesting APIs, at most %v in flight -------------- \n", maxInFlight)
+
+	startTime := time.Now()
+
+	sampler := goroutines.Sample(time.Millisecond)
+
+	var wg sync.WaitGroup
+
+	inFlight := semaphore.New(maxInFlight)
+
+	// the channel is sized to the bound, not to 'numApiCalls': the reading goroutine blocks on the semaphore
+	// so a larger buffer would only hold calls waiting their turn
+	bufferedChannel := make(chan apiDataType, maxInFlight)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			data, open := <- bufferedChannel
+			if !open {
+				break
+			}
+
+			// blocks until a unit is released by a finished fetch
+			if err := inFlight.Acquire(context.Background(), 1); err != nil {
+				fmt.Printf("api %v not requested: %v \n", data.id, err)
+				continue
+			}
+
+			wg.Add(1)
+
+			go fetchBounded(data, inFlight, &wg)
+		}
+	}()
+
+	for i := 0; i < len(allApiCalls); i++ {
+		bufferedChannel <- allApiCalls[i]
+	}
+
+	close(bufferedChannel)
+
+	wg.Wait()
+
+	timeSinceStart := time.Since(startTime)
+
+	fmt.Printf("total API processing time: %v \n", timeSinceStart)
+	fmt.Printf("peak goroutines: %v \n", sampler.Stop())
+}
+
+// 'apiRequestContext' is 'apiRequest()' that stops waiting when 'ctx' is cancelled or its deadline passes
+func apiRequestContext(ctx context.Context, data apiDataType) error {
+	select {
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 'workReport' is which ids completed, which were cancelled and which never started
+// a call which ran past its own 'taskTimeout' while 'ctx' was still live timed out, it was not cancelled by the caller
+type workReport struct {
+	completed  []int
+	cancelled  []int
+	timedOut   []int
+	notStarted []int
+}
+
+// 'workWithContext' is the context-aware variant of 'work()'
+// cancelling 'ctx' stops feeding 'bufferedChannel' and cancels the in-flight goroutines
+// each 'apiRequestContext()' gets its own 'taskTimeout' deadline (0 = no deadline)
+func workWithContext(ctx context.Context, allApiCalls []apiDataType, numApiCalls int, taskTimeout time.Duration) workReport {
+	fmt.Println("start simultaneously requesting 100 APIs (with context) ---")
+
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var report workReport
+
+	bufferedChannel := make(chan apiDataType, numApiCalls)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			data, open := <- bufferedChannel
+			if !open {
+				break
+			}
+
+			// the context was cancelled after the data was written, do not start it
+			if ctx.Err() != nil {
+				mu.Lock()
+				report.notStarted = append(report.notStarted, data.id)
+				mu.Unlock()
+				continue
+			}
+
+			wg.Add(1)
+
+			go func(data apiDataType) {
+				defer wg.Done()
+
+				taskCtx := ctx
+				if taskTimeout > 0 {
+					var cancel context.CancelFunc
+					taskCtx, cancel = context.WithTimeout(ctx, taskTimeout)
+					defer cancel()
+				}
+
+				err := apiRequestContext(taskCtx, data)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					report.completed = append(report.completed, data.id)
+				case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+					report.timedOut = append(report.timedOut, data.id)
+				default:
+					report.cancelled = append(report.cancelled, data.id)
+				}
+			}(data)
+		}
+	}()
+
+	// stop feeding 'bufferedChannel' as soon as the context is cancelled
+	// whatever was not written never started
+	for i := 0; i < len(allApiCalls); i++ {
+		select {
+		case bufferedChannel <- allApiCalls[i]:
+			continue
+		case <-ctx.Done():
+		}
+
+		mu.Lock()
+		for _, data := range allApiCalls[i:] {
+			report.notStarted = append(report.notStarted, data.id)
+		}
+		mu.Unlock()
+		break
+	}
+
+	close(bufferedChannel)
+
+	wg.Wait()
+
+	timeSinceStart := time.Since(startTime)
+
+	fmt.Printf("total API processing time: %v \n", timeSinceStart)
+	fmt.Printf("completed: %v, cancelled: %v, timed out: %v, not started: %v \n", len(report.completed), len(report.cancelled), len(report.timedOut), len(report.notStarted))
+
+	return report
 }
 
 func main() {
 
 	// numApiCalls := 3000
-	numApiCalls := 1000
+	// '-calls 1000000' shows the difference in peak goroutines between 'work()' and 'workBounded()'
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	maxInFlight := flag.Int64("max-in-flight", 100, "maximum goroutines in flight for 'workBounded()'")
+	taskTimeout := flag.Duration("task-timeout", 0, "deadline of each call of 'workWithContext()' (0 = none)")
+	flag.Parse()
+
+	if *maxInFlight < 1 {
+		fmt.Println("'-max-in-flight' must be at least 1")
+		os.Exit(2)
+	}
 
 	// array of api data calls
 	var allApiCalls []apiDataType
 
 	// loop number of api calls and place into array
-	for i := 0; i < numApiCalls; i++ {
+	for i := 0; i < *numApiCalls; i++ {
 		data := apiDataType{ id: i }
 		allApiCalls = append(allApiCalls, data)
 	}
 
 	// call 'work' with all requests to process
-	work(allApiCalls, numApiCalls)
+	work(allApiCalls, *numApiCalls)
+
+	// call 'workBounded' with the same requests, at most 'maxInFlight' at once
+	workBounded(allApiCalls, *maxInFlight)
+
+	// call 'workWithContext' with a parent context cancelled after 50ms
+	// every call takes 100ms so each one in flight is cancelled, or times out first with a '-task-timeout' below 50ms
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+
+	workWithContext(ctx, allApiCalls, *numApiCalls, *taskTimeout)
 }
 
 //	% go run main.go
 //	start simultaneously requesting 100 APIs ------------------
-//	total API processing time: 103.778568ms
+//	total API processing time: 103.637911ms 
+//	peak goroutines: 1002 
+//	start requesting APIs, at most 100 in flight -------------- 
+//	total API processing time: 1.008491298s 
+//	peak goroutines: 103 
+//	start simultaneously requesting 100 APIs (with context) ---
+//	total API processing time: 51.558363ms 
+//	completed: 0, cancelled: 1000, timed out: 0, not started: 0 
+
+// example with '-calls 100000 -max-in-flight 1000' ('peak goroutines' is sampled every 1ms so it can miss the true peak)
+//
+//	% go run main.go -calls 100000 -max-in-flight 1000
+//	start simultaneously requesting 100 APIs ------------------
+//	total API processing time: 480.498142ms 
+//	peak goroutines: 57974 
+//	start requesting APIs, at most 1000 in flight -------------- 
+//	total API processing time: 10.137073505s 
+//	peak goroutines: 1003 
+//	start simultaneously requesting 100 APIs (with context) ---
+//	total API processing time: 84.108645ms 
+//	completed: 0, cancelled: 10318, timed out: 0, not started: 89682 
+
+// example with '-task-timeout 30ms': every call runs past its own deadline before the parent context is cancelled
+//
+//	% go run main.go -task-timeout 30ms
+//	start simultaneously requesting 100 APIs ------------------
+//	total API processing time: 103.862208ms 
+//	peak goroutines: 1002 
+//	start requesting APIs, at most 100 in flight -------------- 
+//	total API processing time: 1.007128059s 
+//	peak goroutines: 103 
+//	start simultaneously requesting 100 APIs (with context) ---
+//	total API processing time: 35.475947ms 
+//	completed: 0, cancelled: 0, timed out: 1000, not started: 0 
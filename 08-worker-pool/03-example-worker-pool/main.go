@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
-	"sync"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
 )
 
 // example demonstrates asychronously processing data employing a 'Worker Pool/Thread Pool'
@@ -16,68 +23,137 @@ import (
 // for huge amount of data processing a computer can run out of memory (running several million tasks)
 // the benefit of a 'worker-pool' is it distributes the process load over time (enables the handling of much greater workloads)
 
+// the worker pool itself (buffered channel + 'WaitGroup') lives in package '08-worker-pool/pool'
+// this example is a thin caller of 'pool.Pool' with 'apiDataType' tasks and 'apiRequest()' as the task function
+// each feature of the pool has its own example after '08-example-schedule' ('09-example-retry', '17-example-breaker', ...)
+
 type apiDataType struct {
 	id int
 }
 
-func apiRequest(data apiDataType) {
+// every 'failEvery' API call fails to demonstrate error reporting (0 disables failures)
+const failEvery = 97
+
+// 'apiRequest' returns the id it processed or an error
+// 'ctx' is cancelled by a 'FailFast' pool after the first error so the simulated call stops waiting
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
 	// fmt.Printf(">>>>>>>>> api %v request \n", data.id)
-	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
 	// fmt.Printf("api %v response <<<<<<<<< \n", data.id)
+
+	if failEvery > 0 && data.id > 0 && data.id%failEvery == 0 {
+		return 0, errors.New("simulated failure")
+	}
+	return data.id, nil
 }
 
-func workerPool(allApiCalls []apiDataType, numberOfWorkers int) {
+func workerPool(ctx context.Context, allApiCalls []apiDataType, numberOfWorkers int, mode pool.Mode, taskTimeout time.Duration) {
 	fmt.Println("start simultaneously requesting 100 APIs ------------------")
 
 	startTime := time.Now()
 
-	var wg sync.WaitGroup
-
-	// use the buffered channel to implement the worker pool
-	// a pool of goroutines ('numberOfWorkers') will listen on the buffered channel for assigned tasks
-	bufferedChannel := make(chan apiDataType, numberOfWorkers)
-
-	// for-loop all 'numberOfWorkers' >>> goroutines <<< to do processing (100 goroutines will process all 'allApiCalls')
-	// once a worker/goroutine is done processig a task, it processes another
-	// the channel is a queue and each worker (goroutine) is continually used to process as many assigned tasks as possible
-	for i := 0; i < numberOfWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			// while loop all open 'bufferedChannels' and task each with 'apiRequest(data)'
-			for {
-				data, open := <- bufferedChannel
-				if !open {
-					break
+	// a pool of goroutines ('numberOfWorkers') will listen on the pool's buffered channel for assigned tasks
+	// cancelling 'ctx' stops feeding the pool and signals the workers, 'taskTimeout' is each 'apiRequest()' deadline
+	workers := pool.NewContext(ctx, numberOfWorkers, apiRequest, pool.WithMode(mode), pool.WithTaskTimeout(taskTimeout))
+
+	// writing 'allApiCalls' to the pool in a goroutine so results can be read at the same time
+	// this read/write cycle continues until the pool is closed (data all processed)
+	// a stopped pool no longer accepts tasks ('Submit' returns 'pool.ErrStopped'), the rest never start
+	var neverSubmitted []int
+	go func() {
+		defer workers.Close()
+		for i := 0; i < len(allApiCalls); i++ {
+			if err := workers.Submit(allApiCalls[i]); err != nil {
+				for _, data := range allApiCalls[i:] {
+					neverSubmitted = append(neverSubmitted, data.id)
 				}
-				apiRequest(data)
+				return
 			}
-		}()
+		}
+	}()
+
+	// while loop the results channel until the pool has processed every task
+	// and collect the ids per 'pool.Status'
+	ids := make(map[pool.Status][]int)
+	for result := range workers.Results() {
+		ids[result.Status] = append(ids[result.Status], result.Task.id)
 	}
 
-	// goroutines have now read/extracted (some) data in the channel (avoiding deadlock/panic)
-	// data has been read/extracted from the channel
-	// so now writing number of read/extracted 'allApiCalls' to 'bufferedChannel'
-	// this read/write cycle continues until the channel is closed (data all processed)
-	for i := 0; i < len(allApiCalls); i++ {
-		bufferedChannel <- allApiCalls[i]
-	}
-
-	close(bufferedChannel)
-
-	wg.Wait()
+	err := workers.Wait()
 
 	timeSinceStart := time.Since(startTime)
 
 	fmt.Printf("total API processing time: %v \n", timeSinceStart)
+
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, failed: %v, cancelled: %v, not started: %v \n", report.Succeeded, report.Failed, report.Cancelled, report.NotStarted+len(neverSubmitted))
+
+	if err != nil {
+		fmt.Printf("stopped: %v \n", err)
+		fmt.Printf("completed ids: %v \n", formatIds(ids[pool.Succeeded]))
+		fmt.Printf("cancelled ids: %v \n", formatIds(ids[pool.Cancelled]))
+		fmt.Printf("never started ids: %v \n", formatIds(append(ids[pool.NotStarted], neverSubmitted...)))
+		return
+	}
+	for _, failure := range report.Failures {
+		fmt.Printf("api %v failed: %v \n", failure.Task.id, failure.Err)
+	}
+}
+
+// 'formatIds' sorts ids and collapses consecutive runs ("0-99, 150, 200-299")
+func formatIds(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	sort.Ints(ids)
+
+	var ranges []string
+	start := ids[0]
+	for i := 1; i <= len(ids); i++ {
+		if i < len(ids) && ids[i] == ids[i-1]+1 {
+			continue
+		}
+		if start == ids[i-1] {
+			ranges = append(ranges, strconv.Itoa(start))
+		} else {
+			ranges = append(ranges, strconv.Itoa(start)+"-"+strconv.Itoa(ids[i-1]))
+		}
+		if i < len(ids) {
+			start = ids[i]
+		}
+	}
+	return strings.Join(ranges, ", ")
 }
 
 func main() {
 
+	// 'pool.CollectAll' runs every call and reports each failure
+	// 'pool.FailFast' stops dispatch on the first failure and cancels in-flight calls
+	failFast := flag.Bool("fail-fast", false, "stop on the first failed API call")
+	// '-timeout' cancels the whole run, '-task-timeout' is the deadline for each 'apiRequest()'
+	timeout := flag.Duration("timeout", 0, "cancel the run after this duration (0 = no limit)")
+	taskTimeout := flag.Duration("task-timeout", 0, "deadline for each API call (0 = no limit)")
+	flag.Parse()
+
 	numApiCalls := 1000
 	numberOfWorkers := 100
 
+	mode := pool.CollectAll
+	if *failFast {
+		mode = pool.FailFast
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
 	var allApiCalls []apiDataType
 
 	for i := 0; i < numApiCalls; i++ {
@@ -85,9 +161,52 @@ func main() {
 		allApiCalls = append(allApiCalls, data)
 	}
 
-	workerPool(allApiCalls, numberOfWorkers)
+	workerPool(ctx, allApiCalls, numberOfWorkers, mode, *taskTimeout)
 }
 
 //	% go run main.go
 //	start simultaneously requesting 100 APIs ------------------
-//	total API processing time: 1.024139554s
+//	total API processing time: 1.009038227s 
+//	succeeded: 990, failed: 10, cancelled: 0, not started: 0 
+//	api 97 failed: simulated failure 
+//	api 194 failed: simulated failure 
+//	api 291 failed: simulated failure 
+//	api 388 failed: simulated failure 
+//	api 485 failed: simulated failure 
+//	api 582 failed: simulated failure 
+//	api 679 failed: simulated failure 
+//	api 776 failed: simulated failure 
+//	api 873 failed: simulated failure 
+//	api 970 failed: simulated failure 
+
+// example with '-fail-fast' ('mode := pool.FailFast'): the first failure (api 97) cancels the calls in flight
+// and the calls still queued never start
+//
+//	% go run main.go -fail-fast
+//	start simultaneously requesting 100 APIs ------------------
+//	total API processing time: 102.853405ms 
+//	succeeded: 99, failed: 1, cancelled: 98, not started: 802 
+//	stopped: simulated failure 
+//	completed ids: 0-96, 98-99 
+//	cancelled ids: 100-197 
+//	never started ids: 198-999 
+
+// example with '-timeout 450ms' (the run is cancelled in its 5th round of 100 calls)
+//
+//	% go run main.go -timeout 450ms
+//	start simultaneously requesting 100 APIs ------------------
+//	total API processing time: 450.841868ms 
+//	succeeded: 396, failed: 4, cancelled: 100, not started: 500 
+//	stopped: context deadline exceeded 
+//	completed ids: 0-96, 98-193, 195-290, 292-387, 389-399 
+//	cancelled ids: 400-499 
+//	never started ids: 500-999 
+
+// example with '-task-timeout 50ms' (every 100ms call misses its own deadline, the run goes on)
+//
+//	% go run main.go -task-timeout 50ms
+//	start simultaneously requesting 100 APIs ------------------
+//	total API processing time: 533.907124ms 
+//	succeeded: 0, failed: 1000, cancelled: 0, not started: 0 
+//	api 56 failed: context deadline exceeded 
+//	...
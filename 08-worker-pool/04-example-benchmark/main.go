@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/bench"
+)
+
+// example compares the '01-example-synch', '02-example-asynch' and '03-example-worker-pool' strategies
+// each strategy runs over a matrix of task counts, simulated API latencies and worker counts (the pooled strategies only)
+// '-strategies work-stealing' runs the pool with one deque per worker instead of the shared channel
+// '-strategies batched' runs a batch pool, each worker sends up to 10 tasks per API call
+// the output is a table (or JSON with '-json') of throughput, p50/p95/p99 task latency, peak goroutines and allocations
+
+// the same strategies are available as 'testing.B' benchmarks:
+//	% go test -bench . -benchtime 3x ./08-worker-pool/bench
+
+func main() {
+
+	strategies := flag.String("strategies", "synch,asynch,worker-pool", "comma separated strategies to run")
+	tasks := flag.String("tasks", "100,1000", "comma separated task counts")
+	latencies := flag.String("latencies", "1ms,10ms", "comma separated simulated API latencies")
+	workers := flag.String("workers", "10,100", "comma separated worker counts (worker-pool, work-stealing and batched only)")
+	asJSON := flag.Bool("json", false, "write JSON instead of a table")
+	flag.Parse()
+
+	var selected []bench.Strategy
+	for _, name := range strings.Split(*strategies, ",") {
+		strategy, ok := bench.Lookup(strings.TrimSpace(name))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown strategy: %v \n", name)
+			os.Exit(2)
+		}
+		selected = append(selected, strategy)
+	}
+
+	taskCounts, err := parseInts(*tasks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-tasks: %v \n", err)
+		os.Exit(2)
+	}
+	workerCounts, err := parseInts(*workers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-workers: %v \n", err)
+		os.Exit(2)
+	}
+	var taskLatencies []time.Duration
+	for _, field := range strings.Split(*latencies, ",") {
+		latency, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-latencies: %v \n", err)
+			os.Exit(2)
+		}
+		taskLatencies = append(taskLatencies, latency)
+	}
+
+	measurements := bench.RunMatrix(selected, bench.Matrix(taskCounts, taskLatencies, workerCounts))
+
+	if *asJSON {
+		err = bench.WriteJSON(os.Stdout, measurements)
+	} else {
+		err = bench.WriteTable(os.Stdout, measurements)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func parseInts(list string) ([]int, error) {
+	var ints []int
+	for _, field := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+//	% go run main.go -tasks 1000 -latencies 10ms
+//	     strategy  tasks  latency  workers  elapsed  tasks/sec     p50      p95      p99  peak goroutines  allocs  alloc bytes
+//	        synch   1000     10ms        -  10.836s       92.3  5.422s  10.291s  10.726s                2    1015         9592
+//	       asynch   1000     10ms        -     14ms    69360.9    14ms     14ms     14ms             1002    5027       693752
+//	  worker-pool   1000     10ms       10   1.082s      924.1   541ms   1.027s   1.071s               15    2086        99624
+//	  worker-pool   1000     10ms      100    104ms     9647.8    52ms    104ms    104ms              105    2492       163448
+
+// the shared channel against work stealing, 0s tasks only measure the scheduling
+// for tiny tasks work stealing about doubles the throughput, for 1ms tasks the task itself dominates and both are the same
+//
+//	% go run main.go -strategies worker-pool,work-stealing -tasks 20000 -latencies 0s,10us,1ms -workers 8,64
+//	       strategy  tasks  latency  workers  elapsed  tasks/sec     p50     p95     p99  peak goroutines  allocs  alloc bytes
+//	    worker-pool  20000       0s        8     43ms   461395.0    20ms    41ms    43ms               13   20088      1294376
+//	  work-stealing  20000       0s        8     25ms   804116.6    12ms    24ms    25ms               12   23632      4418704
+//	    worker-pool  20000       0s       64     43ms   460763.0    22ms    41ms    43ms               69   20331      1346760
+//	  work-stealing  20000       0s       64     24ms   825046.5    13ms    23ms    24ms               68   22957      5492736
+//	    worker-pool  20000     10µs        8    104ms   191468.1    57ms   100ms   103ms               13   20059      1286632
+//	  work-stealing  20000     10µs        8     46ms   439050.7    23ms    43ms    45ms               12   23860      3476864
+//	    worker-pool  20000     10µs       64     55ms   363293.6    28ms    52ms    55ms               69   20287      1319240
+//	  work-stealing  20000     10µs       64     46ms   437558.0    25ms    44ms    45ms               68   23229      3990096
+//	    worker-pool  20000      1ms        8   2.824s     7082.4   1.41s  2.684s  2.796s               13   20056      1286184
+//	  work-stealing  20000      1ms        8   2.903s     6889.4  1.414s  2.761s  2.874s               12   37468      3124272
+//	    worker-pool  20000      1ms       64    420ms    47568.6   214ms   399ms   416ms               69   20285      1319656
+//	  work-stealing  20000      1ms       64    399ms    50119.6   197ms   379ms   395ms               68   36190      3465200
+
+// one API call per task against one call per batch of up to 10 tasks ('pool.NewBatch'), the call latency is the same
+// with few workers batching cuts the elapsed time about 10x, with many workers the batches fill only partly
+//
+//	% go run main.go -strategies worker-pool,batched -tasks 1000 -latencies 1ms,10ms -workers 10,100
+//	     strategy  tasks  latency  workers  elapsed  tasks/sec    p50     p95     p99  peak goroutines  allocs  alloc bytes
+//	  worker-pool   1000      1ms       10    113ms     8833.3   57ms   108ms   112ms               15    2108       107128
+//	      batched   1000      1ms       10     13ms    76739.4    7ms    13ms    13ms               15     957       203680
+//	  worker-pool   1000      1ms      100     15ms    68478.0    8ms    15ms    15ms              105    2595       212568
+//	      batched   1000      1ms      100      3ms   356545.4    3ms     3ms     3ms              104    1328       263488
+//	  worker-pool   1000     10ms       10   1.081s      925.4  538ms  1.026s  1.068s               15    2053        95720
+//	      batched   1000     10ms       10    104ms     9608.2   52ms   104ms   104ms               15     954       202944
+//	  worker-pool   1000     10ms      100    106ms     9390.8   55ms   106ms   106ms              105    2426       155848
+//	      batched   1000     10ms      100     12ms    80047.8   12ms    12ms    12ms              105    1325       262848
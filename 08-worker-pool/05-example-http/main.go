@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/httpload"
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates the worker pool on a real HTTP workload instead of 'time.Sleep()'
+// a local 'httptest' server answers with a configurable latency distribution, error rate and status codes
+// the pool sends 'numRequests' GET requests with 'numberOfWorkers' workers sharing one keep-alive 'http.Client'
+// this is in contrast to '03-example-worker-pool' where 'apiRequest()' only sleeps 100ms
+
+// the report shows the count and latency histogram per HTTP status code and how many connections were reused
+// 5xx and 429 responses are retried with '-retries', other 4xx responses are not ('pool.Permanent')
+
+// '-url' sends the requests to an already running server instead of the bundled one
+
+func main() {
+
+	numRequests := flag.Int("requests", 1000, "number of HTTP requests")
+	numberOfWorkers := flag.Int("workers", 50, "number of workers")
+	latency := flag.Duration("latency", 20*time.Millisecond, "mean server latency")
+	distribution := flag.String("distribution", "exponential", "server latency distribution: fixed, uniform, exponential or normal")
+	errorRate := flag.Float64("error-rate", 0.02, "chance (0-1) the server answers 500")
+	statuses := flag.String("statuses", "200:95,404:3,429:2", "weighted status codes of the other responses")
+	retries := flag.Int("retries", 0, "maximum retries of a 5xx or 429 response")
+	url := flag.String("url", "", "send requests to this server instead of the bundled one")
+	flag.Parse()
+
+	baseURL := *url
+	if baseURL == "" {
+		statusWeights, err := httpload.ParseStatuses(*statuses)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-statuses: %v \n", err)
+			os.Exit(2)
+		}
+
+		server := httpload.NewServer(httpload.ServerConfig{
+			Latency:      *latency,
+			Distribution: httpload.Distribution(*distribution),
+			ErrorRate:    *errorRate,
+			Statuses:     statusWeights,
+		})
+		defer server.Close()
+		baseURL = server.URL
+	}
+
+	client := httpload.NewClient(baseURL, *numberOfWorkers)
+	defer client.CloseIdleConnections()
+
+	options := []pool.Option{pool.WithTaskTimeout(5 * time.Second)}
+	if *retries > 0 {
+		options = append(options, pool.WithRetry(pool.RetryPolicy{
+			MaxAttempts: *retries + 1,
+			BaseDelay:   10 * time.Millisecond,
+			MaxDelay:    time.Second,
+			Jitter:      pool.FullJitter,
+		}))
+	}
+
+	fmt.Printf("start requesting %v from %v workers ------------------ \n", baseURL, *numberOfWorkers)
+
+	startTime := time.Now()
+
+	workers := pool.NewContext(context.Background(), *numberOfWorkers, client.Get, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numRequests; i++ {
+			if err := workers.Submit(i); err != nil {
+				return
+			}
+		}
+	}()
+
+	// each result carries the HTTP status of its last attempt (0 when no response came back)
+	report := httpload.NewStatusReport()
+	for result := range workers.Results() {
+		report.Observe(result.Value)
+	}
+
+	workers.Wait()
+
+	timeSinceStart := time.Since(startTime)
+
+	fmt.Printf("total HTTP processing time: %v (%.1f requests/sec) \n", timeSinceStart, float64(*numRequests)/timeSinceStart.Seconds())
+
+	poolReport := workers.Report()
+	fmt.Printf("succeeded: %v, failed: %v, retries: %v \n", poolReport.Succeeded, poolReport.Failed, poolReport.Retries)
+
+	dialed, reused := client.Connections()
+	fmt.Printf("connections dialed: %v, reused: %v \n", dialed, reused)
+
+	for _, status := range report.Statuses() {
+		histogram := report.Histogram(status)
+		label := http.StatusText(status)
+		if status == 0 {
+			label = "no response"
+		}
+		fmt.Printf("\nstatus %v %v: %v responses, mean: %v, p50: <= %v, p99: <= %v \n", status, label, histogram.Count,
+			histogram.Mean().Round(time.Microsecond), histogram.Quantile(0.5), histogram.Quantile(0.99))
+		fmt.Print(histogram)
+	}
+
+	fmt.Printf("\nall tasks (submit to result): \n%v", poolReport.Latency)
+}
+
+//	% go run main.go
+//	start requesting http://127.0.0.1:35123 from 50 workers ------------------
+//	total HTTP processing time: 462.484801ms (2162.2 requests/sec)
+//	succeeded: 940, failed: 60, retries: 0
+//	connections dialed: 50, reused: 950
+//
+//	status 200 OK: 940 responses, mean: 21.426ms, p50: <= 25ms, p99: <= 100ms
+//	    <= 1ms #                                        3
+//	  <= 2.5ms ########                                 63
+//	    <= 5ms ############                             101
+//	   <= 10ms ###################                      161
+//	   <= 25ms ######################################## 337
+//	   <= 50ms #####################                    179
+//	  <= 100ms ###########                              91
+//	  <= 250ms #                                        5
+//
+//	status 404 Not Found: 21 responses, mean: 18.869ms, p50: <= 25ms, p99: <= 100ms
+//	    <= 5ms ##################                       4
+//	   <= 10ms ##############                           3
+//	   <= 25ms ######################################## 9
+//	   <= 50ms ##################                       4
+//	  <= 100ms #####                                    1
+//
+//	status 429 Too Many Requests: 19 responses, mean: 19.725ms, p50: <= 25ms, p99: <= 50ms
+//	    <= 5ms ######                                   1
+//	   <= 10ms ############################             5
+//	   <= 25ms ##################################       6
+//	   <= 50ms ######################################## 7
+//
+//	status 500 Internal Server Error: 20 responses, mean: 18.6ms, p50: <= 25ms, p99: <= 100ms
+//	    <= 5ms ###########################              4
+//	   <= 10ms #################################        5
+//	   <= 25ms ######################################## 6
+//	   <= 50ms ###########################              4
+//	  <= 100ms #######                                  1
+//
+//	all tasks (submit to result):
+//	   <= 10ms #                                        3
+//	   <= 25ms #########                                142
+//	   <= 50ms ######################################## 618
+//	  <= 100ms ##############                           213
+//	  <= 250ms ##                                       24
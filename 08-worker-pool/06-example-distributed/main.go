@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/distributed"
+)
+
+// example demonstrates the worker pool spread over several processes on localhost
+// the coordinator process owns the queue of 'numApiCalls' simulated API calls
+// each worker process leases calls over TCP ('net/rpc'), runs them on its own local worker pool and reports the results
+// workers send heartbeats while they hold leases, when a worker dies its leases expire and the calls are dispatched again
+
+// run everything from one command (the coordinator starts '-spawn' worker processes of this same program):
+//	% go run main.go -spawn 3 -crash-after 50
+//
+// or start the coordinator and the workers in separate terminals:
+//	% go run main.go -mode coordinator -addr localhost:7070 -spawn 0
+//	% go run main.go -mode worker -addr localhost:7070 -name a
+//	% go run main.go -mode worker -addr localhost:7070 -name b
+
+type apiDataType struct {
+	ID int `json:"id"`
+}
+
+// 'apiRequest' is the '03-example-worker-pool' simulated API call, run inside a worker process
+func apiRequest(ctx context.Context, payload []byte) ([]byte, error) {
+	var data apiDataType
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []byte(strconv.Itoa(data.ID)), nil
+}
+
+func coordinator(addr string, numApiCalls int, spawn int, concurrency int, crashAfter int) {
+	logger := log.New(os.Stdout, "", log.Ltime)
+
+	c := distributed.NewCoordinator(distributed.Config{
+		LeaseTTL:          time.Second,
+		HeartbeatInterval: 250 * time.Millisecond,
+		MaxAttempts:       5,
+		Logger:            logger,
+	})
+	if err := c.Listen(addr); err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Printf("coordinator listening on %v, requesting %v APIs ------------------ \n", c.Addr(), numApiCalls)
+
+	// start worker processes of this same program, the first one crashes after 'crashAfter' calls
+	executable, err := os.Executable()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	var processes []*exec.Cmd
+	for i := 0; i < spawn; i++ {
+		args := []string{"-mode", "worker", "-addr", c.Addr(), "-name", fmt.Sprintf("w%v", i+1), "-workers", strconv.Itoa(concurrency)}
+		if i == 0 && crashAfter > 0 {
+			args = append(args, "-crash-after", strconv.Itoa(crashAfter))
+		}
+		cmd := exec.Command(executable, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			logger.Fatal(err)
+		}
+		processes = append(processes, cmd)
+	}
+
+	startTime := time.Now()
+
+	for i := 0; i < numApiCalls; i++ {
+		payload, _ := json.Marshal(apiDataType{ID: i})
+		c.Submit(payload)
+	}
+	c.Close()
+
+	perWorker := make(map[string]int)
+	redispatched := 0
+	failed := 0
+	for result := range c.Results() {
+		perWorker[result.Worker]++
+		if result.Task.Attempts > 1 {
+			redispatched++
+		}
+		if result.Err != "" {
+			failed++
+		}
+	}
+	c.Wait()
+
+	timeSinceStart := time.Since(startTime)
+
+	for _, cmd := range processes {
+		cmd.Wait()
+	}
+
+	fmt.Printf("total API processing time: %v \n", timeSinceStart)
+	fmt.Printf("re-dispatched after a lease expired: %v, failed: %v \n", redispatched, failed)
+
+	var names []string
+	for name := range perWorker {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("worker %v completed %v calls \n", name, perWorker[name])
+	}
+}
+
+func worker(addr string, name string, concurrency int, crashAfter int) {
+	logger := log.New(os.Stdout, "", log.Ltime)
+
+	// '-crash-after' simulates a worker process dying mid run (no clean shutdown, leases just stop being extended)
+	var calls atomic.Int64
+	handler := func(ctx context.Context, payload []byte) ([]byte, error) {
+		if crashAfter > 0 && calls.Add(1) > int64(crashAfter) {
+			logger.Printf("worker %v: crashing after %v calls", name, crashAfter)
+			os.Exit(1)
+		}
+		return apiRequest(ctx, payload)
+	}
+
+	err := distributed.RunWorker(context.Background(), addr, distributed.WorkerConfig{
+		Name:        name,
+		Concurrency: concurrency,
+		Logger:      logger,
+	}, handler)
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func main() {
+
+	mode := flag.String("mode", "coordinator", "coordinator or worker")
+	addr := flag.String("addr", "localhost:0", "coordinator address")
+	numApiCalls := flag.Int("calls", 1000, "number of API calls (coordinator)")
+	spawn := flag.Int("spawn", 3, "worker processes started by the coordinator")
+	name := flag.String("name", "worker", "worker name")
+	concurrency := flag.Int("workers", 20, "local workers per worker process")
+	crashAfter := flag.Int("crash-after", 0, "worker exits abruptly after this many calls (0 = never)")
+	flag.Parse()
+
+	switch *mode {
+	case "coordinator":
+		coordinator(*addr, *numApiCalls, *spawn, *concurrency, *crashAfter)
+	case "worker":
+		worker(*addr, *name, *concurrency, *crashAfter)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode: %v \n", *mode)
+		os.Exit(2)
+	}
+}
+
+//	% go run main.go -spawn 3 -crash-after 50
+//	coordinator listening on 127.0.0.1:45817, requesting 1000 APIs ------------------ 
+//	23:53:26 coordinator: worker w2-1 registered
+//	23:53:26 coordinator: worker w1-2 registered
+//	23:53:26 worker w2-1: registered with 127.0.0.1:45817
+//	23:53:26 worker w1-2: registered with 127.0.0.1:45817
+//	23:53:26 coordinator: worker w3-3 registered
+//	23:53:26 worker w3-3: registered with 127.0.0.1:45817
+//	23:53:26 worker w1: crashing after 50 calls
+//	23:53:27 coordinator: task 135 lease expired on w1-2, re-dispatching
+//	...
+//	23:53:28 worker w2-1: finished
+//	23:53:28 worker w3-3: finished
+//	total API processing time: 2.791398522s 
+//	re-dispatched after a lease expired: 19, failed: 0 
+//	worker w1-2 completed 32 calls 
+//	worker w2-1 completed 482 calls 
+//	worker w3-3 completed 486 calls 
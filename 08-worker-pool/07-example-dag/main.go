@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates API calls which depend on each other, run on the worker pool as a DAG (directed acyclic graph)
+// for every account: fetch a token first, then the pages with that token, then a summary of the pages
+//
+//	token-3 ---> page-3.1 ---\
+//	        \--> page-3.2 ----> summary-3
+//	         \-> page-3.3 ---/
+//
+// the pages of an account run in parallel (and alongside the calls of the other accounts) once its token is fetched
+// a failed call is not retried here, every call depending on it is 'Skipped' instead of run with missing data
+// this is in contrast to '03-example-worker-pool' where every 'apiDataType' is independent
+
+// 'apiCall' is one node of the graph
+type apiCall struct {
+	kind    string
+	account int
+	page    int
+}
+
+func (c apiCall) id() string {
+	switch c.kind {
+	case "page":
+		return fmt.Sprintf("page-%v.%v", c.account, c.page)
+	default:
+		return fmt.Sprintf("%v-%v", c.kind, c.account)
+	}
+}
+
+// '-fail' is the id of a call which fails, to show its dependents being skipped
+var failID string
+
+// 'apiRequest' simulates each call: a token takes 50ms, a page 100ms and a summary 20ms
+func apiRequest(ctx context.Context, call apiCall) (string, error) {
+	latency := map[string]time.Duration{"token": 50 * time.Millisecond, "page": 100 * time.Millisecond, "summary": 20 * time.Millisecond}[call.kind]
+	select {
+	case <-time.After(latency):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if call.id() == failID {
+		return "", errors.New("simulated failure")
+	}
+	return call.id() + " ok", nil
+}
+
+func main() {
+
+	numAccounts := flag.Int("accounts", 10, "number of accounts")
+	numPages := flag.Int("pages", 5, "number of pages per account")
+	numberOfWorkers := flag.Int("workers", 20, "number of workers")
+	flag.StringVar(&failID, "fail", "page-3.2", "id of the API call that fails (empty = none)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v accounts with %v workers ------------------ \n", *numAccounts, *numberOfWorkers)
+
+	startTime := time.Now()
+
+	graph := pool.NewDAG[string](context.Background(), *numberOfWorkers, apiRequest)
+
+	// submit the summaries first: a dependency may be submitted after the calls depending on it
+	// the graph only starts a call once all of its dependencies have succeeded
+	go func() {
+		defer graph.Close()
+		for account := 0; account < *numAccounts; account++ {
+			summary := apiCall{kind: "summary", account: account}
+			var pages []string
+			for page := 1; page <= *numPages; page++ {
+				pages = append(pages, apiCall{kind: "page", account: account, page: page}.id())
+			}
+			submit(graph, summary, pages...)
+
+			token := apiCall{kind: "token", account: account}
+			for page := 1; page <= *numPages; page++ {
+				submit(graph, apiCall{kind: "page", account: account, page: page}, token.id())
+			}
+			submit(graph, token)
+		}
+
+		// 'report-0' waits for 'export-0', so 'export-0' depending on 'report-0' would close a cycle and is refused
+		// with 'export-0' never submitted, 'report-0' is skipped once the graph is closed
+		submit(graph, apiCall{kind: "report"}, "summary-0", "export-0")
+		submit(graph, apiCall{kind: "export"}, "report-0")
+	}()
+
+	// while loop the results channel until every call has run or was skipped
+	ids := make(map[pool.Status][]string)
+	var order []string
+	for result := range graph.Results() {
+		ids[result.Status] = append(ids[result.Status], result.ID)
+		if result.Status == pool.Succeeded && result.Task.kind != "page" {
+			order = append(order, fmt.Sprintf("%v (%v)", result.ID, time.Since(startTime).Round(10*time.Millisecond)))
+		}
+		if result.Status != pool.Succeeded {
+			fmt.Printf("%v %v: %v \n", result.ID, result.Status, result.Err)
+		}
+	}
+	graph.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	report := graph.Report()
+	fmt.Printf("succeeded: %v, failed: %v, skipped: %v \n", report.Succeeded, report.Failed, report.Skipped)
+	fmt.Printf("tokens and summaries in completion order: %v \n", strings.Join(order, ", "))
+	sort.Strings(ids[pool.Skipped])
+	fmt.Printf("skipped ids: %v \n", strings.Join(ids[pool.Skipped], ", "))
+}
+
+// 'submit' adds 'call' to the graph and prints why it was refused
+func submit(graph *pool.DAG[string, apiCall, string], call apiCall, dependsOn ...string) {
+	if err := graph.Submit(call.id(), call, dependsOn...); err != nil {
+		fmt.Printf("refused: %v \n", err)
+	}
+}
+
+//	% go run main.go
+//	start requesting 10 accounts with 20 workers ------------------ 
+//	refused: pool: dependency cycle: export-0 -> report-0 -> export-0 
+//	report-0 skipped: pool: dependency never submitted: export-0 
+//	page-3.2 failed: simulated failure 
+//	summary-3 skipped: pool: dependency did not succeed: page-3.2 
+//	total API processing time: 373.10046ms 
+//	succeeded: 68, failed: 1, skipped: 2 
+//	tokens and summaries in completion order: token-8 (50ms), token-9 (50ms), token-0 (50ms), token-1 (50ms), token-2 (50ms), token-3 (50ms), token-4 (50ms), token-5 (50ms), token-6 (50ms), token-7 (50ms), summary-0 (270ms), summary-8 (270ms), summary-9 (270ms), summary-4 (270ms), summary-1 (270ms), summary-2 (270ms), summary-5 (270ms), summary-7 (370ms), summary-6 (370ms) 
+//	skipped ids: report-0, summary-3 
+
+// example with '-fail token-7 -accounts 8' (no pages and no summary of account 7 run without a token)
+//
+//	% go run main.go -fail token-7 -accounts 8
+//	...
+//	total API processing time: 272.611433ms 
+//	succeeded: 49, failed: 1, skipped: 7 
+//	...
+//	skipped ids: page-7.1, page-7.2, page-7.3, page-7.4, page-7.5, report-0, summary-7
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates API calls which run later: after a delay, at a time or on a cron schedule
+// a 'pool.Timetable' holds them in a heap and submits each one to the worker pool when it is due
+// -'-pending' one-shot calls are spread over '-duration', every 10th one is cancelled before it is due
+// -a health check runs on the cron spec '-cron' until the run ends
+// the lateness of a call is how long after its due time it started, with one timer for the whole heap
+// the median stays about 1ms for 10 or 100000 pending calls, as long as the workers keep up with the due calls
+// this is in contrast to '03-example-worker-pool' where every 'apiDataType' is submitted right away
+
+// 'apiCall' is one scheduled call and the time it is due (a health check is due on every run of its cron spec)
+type apiCall struct {
+	kind string
+	id   int
+	due  time.Time
+}
+
+// 'apiRequest' returns when it started, the call itself takes 10ms
+func apiRequest(ctx context.Context, call apiCall) (time.Time, error) {
+	started := time.Now()
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return started, ctx.Err()
+	}
+	return started, nil
+}
+
+func main() {
+
+	numPending := flag.Int("pending", 1000, "number of one-shot API calls scheduled over the run")
+	duration := flag.Duration("duration", 3*time.Second, "length of the run")
+	cronSpec := flag.String("cron", "@every 1s", "cron spec of the health check (5 fields or @every)")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	flag.Parse()
+
+	fmt.Printf("start scheduling %v API calls over %v ------------------ \n", *numPending, *duration)
+
+	startTime := time.Now()
+
+	workers := pool.New(*numberOfWorkers, apiRequest)
+	timetable := pool.NewTimetable(workers, nil)
+
+	// the one-shot calls are due at random times of the run (the last 10% are left free)
+	var cancelled int
+	for i := 0; i < *numPending; i++ {
+		delay := time.Duration(rand.Int63n(int64(*duration) * 9 / 10))
+		id, err := timetable.SubmitAt(apiCall{kind: "call", id: i, due: startTime.Add(delay)}, startTime.Add(delay))
+		if err != nil {
+			fmt.Printf("schedule: %v \n", err)
+			return
+		}
+		if i%10 == 9 && timetable.Cancel(id) {
+			cancelled++
+		}
+	}
+
+	// the health check runs until the timetable is stopped
+	healthID, err := timetable.SubmitCron(apiCall{kind: "health"}, *cronSpec)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	next, _ := timetable.Next(healthID)
+	fmt.Printf("pending: %v, cancelled: %v, first health check at +%v \n", timetable.Pending(), cancelled, next.Sub(startTime).Round(time.Millisecond))
+
+	// stop the timetable at the end of the run, then close the pool so 'Results()' ends
+	go func() {
+		time.Sleep(*duration)
+		fmt.Printf("stopped with %v calls pending \n", timetable.Stop())
+		workers.Close()
+	}()
+
+	// while loop the results channel and measure how late each call started
+	var lateness []time.Duration
+	var checks []string
+	for result := range workers.Results() {
+		if result.Task.kind == "health" {
+			checks = append(checks, fmt.Sprintf("+%v", result.Value.Sub(startTime).Round(time.Millisecond)))
+			continue
+		}
+		lateness = append(lateness, result.Value.Sub(result.Task.due))
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	stats := timetable.Stats()
+	fmt.Printf("submitted: %v, cancelled: %v, dropped: %v \n", stats.Submitted, stats.Cancelled, stats.Dropped)
+	if len(lateness) > 0 {
+		sort.Slice(lateness, func(i, j int) bool { return lateness[i] < lateness[j] })
+		fmt.Printf("one-shot calls: %v, lateness p50: %v, p99: %v, max: %v \n", len(lateness),
+			lateness[len(lateness)/2].Round(10*time.Microsecond),
+			lateness[len(lateness)*99/100].Round(10*time.Microsecond),
+			lateness[len(lateness)-1].Round(10*time.Microsecond))
+	}
+	fmt.Printf("health checks started at: %v \n", checks)
+}
+
+//	% go run main.go
+//	start scheduling 1000 API calls over 3s ------------------ 
+//	pending: 901, cancelled: 100, first health check at +1.001s 
+//	stopped with 1 calls pending 
+//	total API processing time: 3.002187658s 
+//	submitted: 902, cancelled: 101, dropped: 0 
+//	one-shot calls: 900, lateness p50: 670µs, p99: 7.11ms, max: 11.55ms 
+//	health checks started at: [+1.002s +2.002s] 
+
+// example with '-pending 100000 -workers 1000' (about 33000 calls per second)
+// some calls are already due while the rest are scheduled, 'pending' is lower than 90000
+//
+//	% go run main.go -pending 100000 -workers 1000
+//	start scheduling 100000 API calls over 3s ------------------ 
+//	pending: 88899, cancelled: 10000, first health check at +1.113s 
+//	stopped with 1 calls pending 
+//	total API processing time: 3.12378636s 
+//	submitted: 90003, cancelled: 10001, dropped: 0 
+//	one-shot calls: 90000, lateness p50: 1.03ms, p99: 72.9ms, max: 120.53ms 
+//	health checks started at: [+1.113s +2.115s +3.114s] 
+
+// with the default 100 workers the pool only runs 10000 calls per second, the due calls wait in the pool lanes
+// the timetable blocks on the full lanes, so the health check runs late too (here not at all)
+//
+//	% go run main.go -pending 100000
+//	...
+//	stopped with 56967 calls pending 
+//	total API processing time: 3.9016451s 
+//	submitted: 33034, cancelled: 66967, dropped: 0 
+//	one-shot calls: 33034, lateness p50: 1.53541s, p99: 2.86513s, max: 2.89868s 
+//	health checks started at: [] 
+
+// example with a 5 field cron spec, '* * * * *' is due at the start of the next minute
+//
+//	% go run main.go -pending 10 -cron "* * * * *"
+//	start scheduling 10 API calls over 3s ------------------ 
+//	pending: 10, cancelled: 1, first health check at +31.698s 
+//	...
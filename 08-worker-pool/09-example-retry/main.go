@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates retrying failed API calls with exponential backoff and full jitter ('pool.RetryPolicy')
+// -'-transient-rate' is the chance an API call fails with a retryable error, it is retried up to '-retries' times
+// -every 97th API call fails for good, those failures are 'pool.Permanent' so they are not retried
+// -the retry budget allows about 20% extra calls, so an outage (a high '-transient-rate') does not multiply the load
+// this is in contrast to '03-example-worker-pool' where a failed call is reported right away
+
+type apiDataType struct {
+	id int
+}
+
+// every 'failEvery' API call fails for good (0 disables those failures)
+const failEvery = 97
+
+var errTransient = errors.New("simulated transient failure")
+
+// 'apiRequest' returns the id it processed or an error
+func apiRequest(transientRate float64) pool.Task[apiDataType, int] {
+	return func(ctx context.Context, data apiDataType) (int, error) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+
+		if failEvery > 0 && data.id > 0 && data.id%failEvery == 0 {
+			return 0, pool.Permanent(errors.New("simulated failure"))
+		}
+		if rand.Float64() < transientRate {
+			return 0, errTransient
+		}
+		return data.id, nil
+	}
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	retries := flag.Int("retries", 3, "maximum retries per API call (0 = no retries)")
+	transientRate := flag.Float64("transient-rate", 0.1, "chance (0-1) an API call fails with a retryable error")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs with up to %v retries ------------------ \n", *numApiCalls, *retries)
+
+	startTime := time.Now()
+
+	// the backoff doubles from 10ms up to 200ms, 'FullJitter' waits a random time up to it
+	// so the retries of calls which failed together do not hit the downstream together again
+	retryBudget := pool.NewRetryBudget(0.2, 10)
+	var options []pool.Option
+	if *retries > 0 {
+		options = append(options, pool.WithRetry(pool.RetryPolicy{
+			MaxAttempts: *retries + 1,
+			BaseDelay:   10 * time.Millisecond,
+			MaxDelay:    200 * time.Millisecond,
+			Jitter:      pool.FullJitter,
+			Budget:      retryBudget,
+		}))
+	}
+	workers := pool.New(*numberOfWorkers, apiRequest(*transientRate), options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel and count how many attempts each call needed
+	attempts := make(map[int]int)
+	for result := range workers.Results() {
+		attempts[result.Attempts]++
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, failed: %v \n", report.Succeeded, report.Failed)
+	fmt.Printf("retries: %v, calls per attempt count: %v \n", report.Retries, attempts)
+	if denied := retryBudget.Denied(); denied > 0 {
+		fmt.Printf("retries denied by the retry budget: %v \n", denied)
+	}
+	for _, failure := range report.Failures {
+		fmt.Printf("api %v failed after %v attempt(s): %v \n", failure.Task.id, failure.Attempts, failure.Err)
+	}
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs with up to 3 retries ------------------ 
+//	total API processing time: 1.352721629s 
+//	succeeded: 990, failed: 10 
+//	retries: 105, calls per attempt count: map[1:906 2:83 3:11] 
+//	api 97 failed after 1 attempt(s): simulated failure 
+//	api 194 failed after 1 attempt(s): simulated failure 
+//	api 291 failed after 1 attempt(s): simulated failure 
+//	api 388 failed after 1 attempt(s): simulated failure 
+//	api 485 failed after 1 attempt(s): simulated failure 
+//	api 582 failed after 1 attempt(s): simulated failure 
+//	api 679 failed after 1 attempt(s): simulated failure 
+//	api 776 failed after 1 attempt(s): simulated failure 
+//	api 873 failed after 1 attempt(s): simulated failure 
+//	api 970 failed after 1 attempt(s): simulated failure 
+
+// example with '-retries 0' (every transient failure is reported)
+//
+//	% go run main.go -retries 0
+//	start requesting 1000 APIs with up to 0 retries ------------------ 
+//	total API processing time: 1.010291671s 
+//	succeeded: 877, failed: 123 
+//	retries: 0, calls per attempt count: map[1:1000] 
+//	...
+
+// example with '-transient-rate 0.5' (half the calls fail: the budget stops the retries at about 20% extra calls)
+//
+//	% go run main.go -transient-rate 0.5
+//	start requesting 1000 APIs with up to 3 retries ------------------ 
+//	total API processing time: 1.31437243s 
+//	succeeded: 614, failed: 386 
+//	retries: 209, calls per attempt count: map[1:824 2:147 3:25 4:4] 
+//	retries denied by the retry budget: 375 
+//	api 61 failed after 1 attempt(s): simulated transient failure 
+//	...
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates a token bucket shared by every worker in front of 'apiRequest()' ('pool.RateLimiter')
+// -'-rate' tokens are added per second, up to '-burst' of them are saved for a burst of calls
+// -'-max-per-second' is a hard cap per wall-clock second on top of the bucket (some APIs count calls that way)
+// a worker waits for a token before each call, so 100 workers make at most '-rate' calls per second
+// this is in contrast to '03-example-worker-pool' where 100 workers make about 1000 calls per second
+
+type apiDataType struct {
+	id int
+}
+
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	rate := flag.Float64("rate", 200, "API calls per second allowed by the rate limiter")
+	burst := flag.Int("burst", 20, "rate limiter burst size")
+	maxPerSecond := flag.Int("max-per-second", 0, "hard cap of API calls per wall-clock second (0 = no cap)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs at %v per second ------------------ \n", *numApiCalls, *rate)
+
+	startTime := time.Now()
+
+	rateLimiter := pool.NewRateLimiter(*rate, *burst, *maxPerSecond)
+	workers := pool.New(*numberOfWorkers, apiRequest, pool.WithRateLimiter(rateLimiter))
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel and count the calls finished in each second of the run
+	perSecond := make(map[int]int)
+	for range workers.Results() {
+		perSecond[int(time.Since(startTime)/time.Second)]++
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	stats := rateLimiter.Stats()
+	fmt.Printf("achieved QPS: %.1f, time waiting on the rate limiter: %v (all workers) \n",
+		float64(stats.Acquired)/time.Since(startTime).Seconds(), stats.Waited.Round(time.Millisecond))
+	for second := 0; second < len(perSecond); second++ {
+		fmt.Printf("second %v: %v calls \n", second, perSecond[second])
+	}
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs at 200 per second ------------------ 
+//	total API processing time: 5.001690091s 
+//	achieved QPS: 199.9, time waiting on the rate limiter: 6m14.808s (all workers) 
+//	second 0: 199 calls 
+//	second 1: 200 calls 
+//	second 2: 200 calls 
+//	second 3: 200 calls 
+//	second 4: 200 calls 
+//	second 5: 1 calls 
+
+// example with '-rate 1000 -burst 100 -max-per-second 150' (the cap decides, not the bucket)
+// the first second of the run spans two wall-clock seconds, so it gets calls of both
+//
+//	% go run main.go -rate 1000 -burst 100 -max-per-second 150
+//	start requesting 1000 APIs at 1000 per second ------------------ 
+//	total API processing time: 5.948545576s 
+//	achieved QPS: 168.1, time waiting on the rate limiter: 8m13.635s (all workers) 
+//	second 0: 250 calls 
+//	second 1: 150 calls 
+//	second 2: 150 calls 
+//	second 3: 150 calls 
+//	second 4: 150 calls 
+//	second 5: 150 calls 
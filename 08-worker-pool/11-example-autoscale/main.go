@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates a worker pool whose size changes while it runs
+// -'-max-workers' turns on the autoscaler ('pool.WithAutoscale'): it adds workers while tasks queue up
+// and removes the ones idle for longer than 'IdleTimeout', never going below '-workers'
+// -'-resize' calls 'Resize()' by hand halfway through the run instead
+// this is in contrast to '03-example-worker-pool' where the 100 workers are fixed
+
+type apiDataType struct {
+	id int
+}
+
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 10, "number of workers (the minimum with '-max-workers')")
+	maxWorkers := flag.Int("max-workers", 100, "autoscale up to this many workers (0 = fixed size)")
+	resize := flag.Int("resize", 0, "resize the pool to this many workers halfway through the calls (0 = no resize)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs with %v workers ------------------ \n", *numApiCalls, *numberOfWorkers)
+
+	startTime := time.Now()
+
+	// the autoscaler looks at the queue every 50ms and adds up to 10 workers at a time
+	var options []pool.Option
+	if *maxWorkers > *numberOfWorkers {
+		options = append(options, pool.WithAutoscale(pool.AutoscalePolicy{
+			MinWorkers:  *numberOfWorkers,
+			MaxWorkers:  *maxWorkers,
+			Step:        10,
+			Interval:    50 * time.Millisecond,
+			IdleTimeout: 200 * time.Millisecond,
+		}))
+	}
+	workers := pool.New(*numberOfWorkers, apiRequest, options...)
+
+	// display the pool size whenever it changed (checked every 100ms until every result is in)
+	finished := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		lastSize := *numberOfWorkers
+		for {
+			select {
+			case <-finished:
+				return
+			case <-ticker.C:
+			}
+			if size := workers.Size(); size != lastSize {
+				fmt.Printf("+%v workers: %v (queue depth: %v) \n", time.Since(startTime).Round(100*time.Millisecond), size, workers.QueueDepth())
+				lastSize = size
+			}
+		}
+	}()
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			if *resize > 0 && i == *numApiCalls/2 {
+				workers.Resize(*resize)
+			}
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	for range workers.Results() {
+	}
+	close(finished)
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, failed: %v \n", report.Succeeded, report.Failed)
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs with 10 workers ------------------ 
+//	+100ms workers: 20 (queue depth: 10) 
+//	+200ms workers: 50 (queue depth: 10) 
+//	+300ms workers: 60 (queue depth: 10) 
+//	+400ms workers: 90 (queue depth: 10) 
+//	+500ms workers: 100 (queue depth: 10) 
+//	total API processing time: 1.259329212s 
+//	succeeded: 1000, failed: 0 
+
+// example with '-max-workers 0' (10 fixed workers make 100 calls per second)
+//
+//	% go run main.go -max-workers 0
+//	start requesting 1000 APIs with 10 workers ------------------ 
+//	total API processing time: 10.059800939s 
+//	succeeded: 1000, failed: 0 
+
+// example with '-max-workers 0 -resize 100' (the second half of the calls runs 10 times faster)
+// the workers exit as the last calls finish, the last size displayed is the pool going away
+//
+//	% go run main.go -max-workers 0 -resize 100
+//	start requesting 1000 APIs with 10 workers ------------------ 
+//	+4.9s workers: 100 (queue depth: 10) 
+//	+5.4s workers: 20 (queue depth: 0) 
+//	total API processing time: 5.432701069s 
+//	succeeded: 1000, failed: 0 
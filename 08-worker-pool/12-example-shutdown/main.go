@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates stopping a worker pool on ^C ('SIGINT') or 'SIGTERM'
+// -'-shutdown drain' stops submitting and lets the queued and in-flight calls finish within '-grace' ('Shutdown()')
+// a second signal during the drain aborts it
+// -'-shutdown abort' cancels the in-flight calls right away, the queued ones never start ('Abort()')
+// the exit code tells how the run ended: 0 not interrupted, 'exitDrained' or 'exitAborted'
+// this is in contrast to '03-example-worker-pool' where ^C kills the program with the calls in flight
+
+type apiDataType struct {
+	id int
+}
+
+// 'apiRequest' returns the id it processed, an aborted pool cancels 'ctx'
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+// exit codes of a run stopped by 'SIGINT' (^C) or 'SIGTERM'
+// 'exitDrained': every queued API call finished within the grace period, 'exitAborted': API calls were cancelled or never started
+const (
+	exitDrained = 3
+	exitAborted = 4
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// 'run' is the body of 'main()', it returns the exit code so the deferred 'signal.Stop' runs before 'os.Exit'
+func run() int {
+
+	numApiCalls := flag.Int("calls", 3000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	shutdownMode := flag.String("shutdown", "drain", "on SIGINT/SIGTERM: drain or abort")
+	grace := flag.Duration("grace", 5*time.Second, "drain grace period before the run is aborted")
+	flag.Parse()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	fmt.Printf("start requesting %v APIs, ^C to stop ------------------ \n", *numApiCalls)
+
+	startTime := time.Now()
+
+	workers := pool.New(*numberOfWorkers, apiRequest)
+
+	// the first signal stops the pool ('Shutdown' drains, 'Abort' cancels), the exit code is sent to 'stopped'
+	stopped := make(chan int, 1)
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-workers.Done():
+			stopped <- 0
+			return
+		}
+
+		if *shutdownMode == "abort" {
+			fmt.Printf("%v: aborting, cancelling %v in-flight API calls \n", sig, workers.Busy())
+			workers.Abort()
+			stopped <- exitAborted
+			return
+		}
+
+		fmt.Printf("%v: draining %v queued and %v in-flight API calls (grace period: %v, ^C again to abort) \n", sig, workers.QueueDepth(), workers.Busy(), *grace)
+		graceCtx, cancel := context.WithTimeout(context.Background(), *grace)
+		defer cancel()
+		go func() {
+			select {
+			case sig := <-signals:
+				fmt.Printf("%v: aborting the drain \n", sig)
+				cancel()
+			case <-graceCtx.Done():
+			}
+		}()
+
+		if err := workers.Shutdown(graceCtx); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				fmt.Printf("grace period of %v is over, aborted \n", *grace)
+			}
+			stopped <- exitAborted
+			return
+		}
+		stopped <- exitDrained
+	}()
+
+	// a stopped pool no longer accepts tasks ('Submit' returns 'pool.ErrStopped'), the rest are never submitted
+	var neverSubmitted []int
+	submitted := make(chan struct{})
+	go func() {
+		defer close(submitted)
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			if err := workers.Submit(apiDataType{id: i}); err != nil {
+				for id := i; id < *numApiCalls; id++ {
+					neverSubmitted = append(neverSubmitted, id)
+				}
+				return
+			}
+		}
+	}()
+
+	ids := make(map[pool.Status][]int)
+	for result := range workers.Results() {
+		ids[result.Status] = append(ids[result.Status], result.Task.id)
+	}
+
+	// a signal closes the pool while the submitting goroutine may still be running
+	<-submitted
+	err := workers.Wait()
+	exitCode := <-stopped
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, cancelled: %v, not started: %v \n", report.Succeeded, report.Cancelled, report.NotStarted+len(neverSubmitted))
+	if err != nil {
+		fmt.Printf("stopped: %v \n", err)
+		fmt.Printf("completed ids: %v \n", formatIds(ids[pool.Succeeded]))
+		fmt.Printf("cancelled ids: %v \n", formatIds(ids[pool.Cancelled]))
+		fmt.Printf("never started ids: %v \n", formatIds(append(ids[pool.NotStarted], neverSubmitted...)))
+		return exitCode
+	}
+	if len(neverSubmitted) > 0 {
+		fmt.Printf("drained, never submitted ids: %v \n", formatIds(neverSubmitted))
+	}
+	return exitCode
+}
+
+// 'formatIds' sorts ids and collapses consecutive runs ("0-99, 150, 200-299")
+func formatIds(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	sort.Ints(ids)
+
+	var ranges []string
+	start := ids[0]
+	for i := 1; i <= len(ids); i++ {
+		if i < len(ids) && ids[i] == ids[i-1]+1 {
+			continue
+		}
+		if start == ids[i-1] {
+			ranges = append(ranges, strconv.Itoa(start))
+		} else {
+			ranges = append(ranges, strconv.Itoa(start)+"-"+strconv.Itoa(ids[i-1]))
+		}
+		if i < len(ids) {
+			start = ids[i]
+		}
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// example stopped with ^C after 1 sec ('-shutdown drain', the default)
+// the queued calls finish, the rest are never submitted, the exit code is 'exitDrained'
+//
+//	% go run main.go
+//	start requesting 3000 APIs, ^C to stop ------------------ 
+//	^Cinterrupt: draining 100 queued and 100 in-flight API calls (grace period: 5s, ^C again to abort) 
+//	total API processing time: 1.20855726s 
+//	succeeded: 1101, cancelled: 0, not started: 1899 
+//	drained, never submitted ids: 1101-2999 
+//	exit status 3
+
+// example with '-shutdown abort' stopped with 'kill' ('SIGTERM') after 1 sec
+// the in-flight calls are cancelled, the exit code is 'exitAborted'
+//
+//	% go run main.go -shutdown abort
+//	start requesting 3000 APIs, ^C to stop ------------------ 
+//	terminated: aborting, cancelling 100 in-flight API calls 
+//	total API processing time: 1.000151944s 
+//	succeeded: 900, cancelled: 100, not started: 2000 
+//	stopped: pool: aborted 
+//	completed ids: 0-899 
+//	cancelled ids: 900-999 
+//	never started ids: 1000-2999 
+//	exit status 4
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates a worker pool which survives a restart: every task goes through a write-ahead journal
+// ('pool.Journal'), a run that is stopped (or crashes) is resumed by running it again
+// -a fresh (or finished) journal gets every task up front (a durable queue), the pool recovers them all with 'Recover()'
+// -a journal with unfinished tasks is a restart: only those tasks run again
+// -'-journal-sync' is how often the journal is flushed to disk, it is compacted every '-journal-compact' finished tasks
+// this is in contrast to '03-example-worker-pool' where the queued calls are lost when the program stops
+
+type apiDataType struct {
+	id int
+}
+
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// 'run' is the body of 'main()', it returns the exit code so the deferred 'journal.Close' runs before 'os.Exit'
+func run() int {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	journalPath := flag.String("journal", "tasks.journal", "task journal file")
+	journalSync := flag.String("journal-sync", "always", "journal fsync policy: always, interval or never")
+	journalCompact := flag.Int("journal-compact", 500, "compact the journal after this many finished tasks")
+	// '-timeout' stops the run early to leave unfinished tasks in the journal
+	timeout := flag.Duration("timeout", 0, "cancel the run after this duration (0 = no limit)")
+	flag.Parse()
+
+	syncPolicy := map[string]pool.SyncPolicy{"always": pool.SyncAlways, "interval": pool.SyncInterval, "never": pool.SyncNever}
+	journal, err := pool.OpenJournal(*journalPath, pool.JournalOptions[apiDataType]{
+		Sync:         syncPolicy[*journalSync],
+		SyncInterval: 100 * time.Millisecond,
+		CompactEvery: *journalCompact,
+		// 'apiDataType.id' is unexported so the journal stores the bare id
+		Encode: func(data apiDataType) ([]byte, error) { return json.Marshal(data.id) },
+		Decode: func(raw []byte) (apiDataType, error) {
+			var data apiDataType
+			err := json.Unmarshal(raw, &data.id)
+			return data, err
+		},
+	})
+	if err != nil {
+		fmt.Printf("journal: %v \n", err)
+		return 1
+	}
+	defer journal.Close()
+
+	if journal.Pending() == 0 {
+		for i := 0; i < *numApiCalls; i++ {
+			if err := journal.Add(apiDataType{id: i}); err != nil {
+				fmt.Printf("journal: %v \n", err)
+				return 1
+			}
+		}
+		fmt.Printf("start requesting %v APIs ------------------ \n", *numApiCalls)
+	} else {
+		fmt.Printf("resume requesting %v unfinished APIs ------------------ \n", journal.Pending())
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+
+	workers := pool.NewContext(ctx, *numberOfWorkers, apiRequest, pool.WithJournal(journal))
+
+	// the tasks recorded as unfinished are queued, tasks not queued because the pool stopped stay in the journal
+	go func() {
+		defer workers.Close()
+		recovered, err := workers.Recover()
+		if err != nil && !errors.Is(err, pool.ErrStopped) {
+			fmt.Printf("journal: %v \n", err)
+		}
+		fmt.Printf("queued %v unfinished tasks from the journal \n", recovered)
+	}()
+
+	for range workers.Results() {
+	}
+	err = workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, cancelled: %v, not started: %v \n", report.Succeeded, report.Cancelled, report.NotStarted)
+	if err != nil {
+		fmt.Printf("stopped: %v, %v tasks left in the journal, run again to resume \n", err, journal.Pending())
+		return 1
+	}
+	fmt.Printf("done, %v tasks left in the journal \n", journal.Pending())
+	return 0
+}
+
+// example where the first run is stopped after 450ms and the second run resumes it
+// the 100 calls cancelled in flight and the 500 never queued are the 600 unfinished tasks
+//
+//	% go run main.go -timeout 450ms
+//	start requesting 1000 APIs ------------------ 
+//	queued 601 unfinished tasks from the journal 
+//	total API processing time: 450.801092ms 
+//	succeeded: 400, cancelled: 100, not started: 101 
+//	stopped: context deadline exceeded, 600 tasks left in the journal, run again to resume 
+//	exit status 1
+//	% go run main.go
+//	resume requesting 600 unfinished APIs ------------------ 
+//	queued 600 unfinished tasks from the journal 
+//	total API processing time: 639.780361ms 
+//	succeeded: 600, cancelled: 0, not started: 0 
+//	done, 0 tasks left in the journal 
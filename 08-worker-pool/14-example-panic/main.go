@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates a worker pool which survives panicking tasks ('pool.PanicPolicy')
+// a panic inside 'apiRequest()' would crash the whole program, every worker recovers it instead:
+// the worker is replaced, the call runs again and after '-max-panics' panics it is quarantined
+// this is in contrast to '03-example-worker-pool' where one bad call takes every other call down with it
+
+type apiDataType struct {
+	id int
+}
+
+// every 'panicEvery' API call panics ('-panic-every')
+var panicEvery int
+
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	if panicEvery > 0 && data.id%panicEvery == panicEvery-1 {
+		var response map[string]int
+		response["id"] = data.id // assignment to entry in nil map
+	}
+	return data.id, nil
+}
+
+// 'firstFrames' keeps the goroutine line and the first 'n' frames of a stack trace
+// the frames of 'panic()' and of the pool's recover are skipped so the panicking function comes first
+func firstFrames(stack string, n int) string {
+	lines := strings.Split(stack, "\n")
+	var kept []string
+	for i := 1; i+1 < len(lines) && len(kept) < 2*n; i += 2 {
+		if strings.HasPrefix(lines[i], "panic(") || strings.Contains(lines[i], "runtime/debug.Stack") ||
+			strings.Contains(lines[i], "pool.(*Pool[...]).worker.func1") || strings.Contains(lines[i], "recoverWorker") {
+			continue
+		}
+		kept = append(kept, lines[i], lines[i+1])
+	}
+	return lines[0] + "\n" + strings.Join(kept, "\n") + "\n"
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	flag.IntVar(&panicEvery, "panic-every", 100, "every nth API call panics (0 = never)")
+	maxPanics := flag.Int("max-panics", 3, "quarantine an API call after this many panics")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs ------------------ \n", *numApiCalls)
+
+	startTime := time.Now()
+
+	workers := pool.New(*numberOfWorkers, apiRequest, pool.WithPanicPolicy(pool.PanicPolicy{MaxPanics: *maxPanics}))
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	var quarantined []int
+	for result := range workers.Results() {
+		if result.Status == pool.Quarantined {
+			quarantined = append(quarantined, result.Task.id)
+		}
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, quarantined: %v \n", report.Succeeded, report.Quarantined)
+	if len(report.Panics) > 0 {
+		sort.Ints(quarantined)
+		fmt.Printf("recovered panics: %v, quarantined ids: %v \n", len(report.Panics), quarantined)
+		first := report.Panics[0]
+		fmt.Printf("first panic in api %v: %v \n%v", first.Task.id, first.Value, firstFrames(first.Stack, 4))
+	}
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs ------------------ 
+//	total API processing time: 1.309505381s 
+//	succeeded: 990, quarantined: 10 
+//	recovered panics: 30, quarantined ids: [99 199 299 399 499 599 699 799 899 999] 
+//	first panic in api 99: assignment to entry in nil map 
+//	goroutine 108 [running]:
+//	main.apiRequest({0x85e158, 0x3d5b3e926050}, {0x0?})
+//		/root/module/08-worker-pool/14-example-panic/main.go:34 +0xc8
+//	github.com/alexsmith716/go-concurrency/08-worker-pool/pool.(*Pool[...]).callTask(0x0?, {0x85e158?, 0x3d5b3e926050?}, {0x0?})
+//		/root/module/08-worker-pool/pool/pool.go:450 +0x91
+//	...
+
+// example with '-max-panics 1' (a call is quarantined on its first panic, the run is 300ms shorter)
+//
+//	% go run main.go -max-panics 1
+//	start requesting 1000 APIs ------------------ 
+//	total API processing time: 1.01658184s 
+//	succeeded: 990, quarantined: 10 
+//	recovered panics: 10, quarantined ids: [99 199 299 399 499 599 699 799 899 999] 
+//	...
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates how the worker pool picks the next API call
+// -priority lanes ('SubmitPriority'): every 10th call is urgent ('pool.High'), the last 3 of every 10 are bulk work ('pool.Low')
+// the dispatcher serves the high lane first, so urgent calls wait least and bulk calls most
+// -'-work-stealing' gives each worker its own deque and lets idle workers steal, the lanes are not used then
+// -'-ordered-window' sends the results in submission order, at most that many calls are submitted and not yet sent
+// this is in contrast to '03-example-worker-pool' where the calls run in submission order and finish in any order
+
+type apiDataType struct {
+	id int
+}
+
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+// 'priorityOf' mixes priorities: every 10th call is urgent, the last 3 of every 10 are bulk work
+func priorityOf(data apiDataType) pool.Priority {
+	switch data.id % 10 {
+	case 0:
+		return pool.High
+	case 7, 8, 9:
+		return pool.Low
+	}
+	return pool.Normal
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	workStealing := flag.Bool("work-stealing", false, "schedule API calls with per-worker deques and work stealing")
+	orderedWindow := flag.Int("ordered-window", 0, "send results in submission order with this reorder window (0 = completion order)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs ------------------ \n", *numApiCalls)
+
+	startTime := time.Now()
+
+	var options []pool.Option
+	if *workStealing {
+		options = append(options, pool.WithScheduler(pool.WorkStealing))
+	}
+	if *orderedWindow > 0 {
+		options = append(options, pool.WithOrderedResults(*orderedWindow))
+	}
+	workers := pool.New(*numberOfWorkers, apiRequest, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			data := apiDataType{id: i}
+			workers.SubmitPriority(data, priorityOf(data))
+		}
+	}()
+
+	// while loop the results channel and collect the queue wait of each priority lane
+	// and count the results arriving after a result with a higher id (out of submission order)
+	laneWaits := make(map[pool.Priority][]time.Duration)
+	outOfOrder, lastId := 0, -1
+	for result := range workers.Results() {
+		if result.Task.id < lastId {
+			outOfOrder++
+		}
+		lastId = max(lastId, result.Task.id)
+		laneWaits[result.Priority] = append(laneWaits[result.Priority], result.Wait)
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	for _, priority := range []pool.Priority{pool.High, pool.Normal, pool.Low} {
+		waits := laneWaits[priority]
+		if len(waits) == 0 {
+			continue
+		}
+		sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+		var total time.Duration
+		for _, wait := range waits {
+			total += wait
+		}
+		fmt.Printf("%v lane: %v tasks, queue wait avg: %v, p95: %v, max: %v \n", priority, len(waits),
+			(total / time.Duration(len(waits))).Round(time.Millisecond),
+			waits[len(waits)*95/100].Round(time.Millisecond),
+			waits[len(waits)-1].Round(time.Millisecond))
+	}
+	fmt.Printf("results out of submission order: %v, reorder buffer peak: %v \n", outOfOrder, workers.ReorderStats().Peak)
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs ------------------ 
+//	total API processing time: 1.009106589s 
+//	high lane: 100 tasks, queue wait avg: 2ms, p95: 0s, max: 100ms 
+//	normal lane: 600 tasks, queue wait avg: 110ms, p95: 202ms, max: 202ms 
+//	low lane: 300 tasks, queue wait avg: 273ms, p95: 404ms, max: 504ms 
+//	results out of submission order: 920, reorder buffer peak: 0 
+
+// example with '-work-stealing' (every lane waits the same, the deques do not know about priorities)
+//
+//	% go run main.go -work-stealing
+//	start requesting 1000 APIs ------------------ 
+//	total API processing time: 1.00988696s 
+//	high lane: 100 tasks, queue wait avg: 454ms, p95: 908ms, max: 908ms 
+//	normal lane: 600 tasks, queue wait avg: 454ms, p95: 908ms, max: 908ms 
+//	low lane: 300 tasks, queue wait avg: 454ms, p95: 908ms, max: 908ms 
+//	results out of submission order: 959, reorder buffer peak: 0 
+
+// example with '-ordered-window 100' (results in submission order, at most 100 calls submitted and not yet sent)
+// the high lane still runs first, its results wait in the reorder buffer for the earlier ids
+// the window also holds back the submission, so the normal and low lanes hardly wait
+//
+//	% go run main.go -ordered-window 100
+//	start requesting 1000 APIs ------------------ 
+//	total API processing time: 1.010943111s 
+//	high lane: 100 tasks, queue wait avg: 9ms, p95: 97ms, max: 101ms 
+//	normal lane: 600 tasks, queue wait avg: 0s, p95: 0s, max: 2ms 
+//	low lane: 300 tasks, queue wait avg: 0s, p95: 0s, max: 3ms 
+//	results out of submission order: 0, reorder buffer peak: 74 
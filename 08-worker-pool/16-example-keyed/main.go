@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates API calls which must not overlap: every call belongs to account 'id % accounts'
+// the calls of one account run one at a time in submission order ('SubmitKey'), calls of different accounts in parallel
+// with 50 accounts of 20 calls each at most 50 of the 100 workers are busy, the run takes 20 rounds of 100ms
+// this is in contrast to '03-example-worker-pool' where any two calls may run at the same time
+
+type apiDataType struct {
+	id      int
+	account string
+}
+
+// 'accounts' records the calls running per account, the most that ran at once and the calls run out of order
+type accounts struct {
+	mu         sync.Mutex
+	running    map[string]int
+	last       map[string]int
+	overlap    int
+	outOfOrder int
+}
+
+// 'apiRequest' is a 100ms call which records when its account had another call in flight
+func (a *accounts) apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	a.mu.Lock()
+	a.running[data.account]++
+	if a.running[data.account] > 1 {
+		a.overlap++
+	}
+	if last, ok := a.last[data.account]; ok && last > data.id {
+		a.outOfOrder++
+	}
+	a.last[data.account] = data.id
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		a.running[data.account]--
+		a.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	numAccounts := flag.Int("accounts", 50, "run the API calls of each of this many accounts one at a time")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs of %v accounts ------------------ \n", *numApiCalls, *numAccounts)
+
+	startTime := time.Now()
+
+	calls := &accounts{running: make(map[string]int), last: make(map[string]int)}
+	workers := pool.New(*numberOfWorkers, calls.apiRequest)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			data := apiDataType{id: i, account: fmt.Sprint("account-", i%*numAccounts)}
+			workers.SubmitKey(data.account, data)
+		}
+	}()
+
+	// sample how many workers are busy while the results come in
+	busy := 0
+	for range workers.Results() {
+		busy = max(busy, workers.Busy())
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	fmt.Printf("succeeded: %v, most workers busy: %v \n", workers.Report().Succeeded, busy)
+	fmt.Printf("calls which overlapped a call of the same account: %v, ran out of order: %v \n", calls.overlap, calls.outOfOrder)
+}
+
+//	% go run main.go
+//	start requesting 1000 APIs of 50 accounts ------------------ 
+//	total API processing time: 2.01492608s 
+//	succeeded: 1000, most workers busy: 50 
+//	calls which overlapped a call of the same account: 0, ran out of order: 0 
+
+// example with '-accounts 1000' (every call has its own account, the pool runs like '03-example-worker-pool')
+//
+//	% go run main.go -accounts 1000
+//	start requesting 1000 APIs of 1000 accounts ------------------ 
+//	total API processing time: 1.00844963s 
+//	succeeded: 1000, most workers busy: 100 
+//	calls which overlapped a call of the same account: 0, ran out of order: 0 
+
+// example with '-accounts 10' (100 calls per account, 10 busy workers)
+//
+//	% go run main.go -accounts 10
+//	start requesting 1000 APIs of 10 accounts ------------------ 
+//	total API processing time: 10.037556252s 
+//	succeeded: 1000, most workers busy: 10 
+//	calls which overlapped a call of the same account: 0, ran out of order: 0 
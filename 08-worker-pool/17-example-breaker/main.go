@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates a circuit breaker in front of a downstream which goes down for a while ('pool.CircuitBreaker')
+// -'-outage-at' and '-outage-for' simulate the outage: every API call in that window of the run fails
+// -without a breaker every worker keeps calling the downstream, each call in the outage fails
+// -'-breaker fail' stops calling it once half of the recent calls failed, the refused calls fail right away
+// -'-breaker requeue' puts the refused calls back, they run once the trial calls closed the breaker again
+// this is in contrast to '03-example-worker-pool' where the calls do not know about each other's failures
+
+type apiDataType struct {
+	id int
+}
+
+var errOutage = errors.New("simulated outage")
+
+// 'downstream' is down from 'start + at' for 'length'
+type downstream struct {
+	start      time.Time
+	at, length time.Duration
+}
+
+func (d downstream) down() bool {
+	since := time.Since(d.start)
+	return since >= d.at && since < d.at+d.length
+}
+
+// 'apiRequest' is a 100ms call which fails while the downstream is down
+func (d downstream) apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	if d.down() {
+		return 0, errOutage
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	outageAt := flag.Duration("outage-at", 300*time.Millisecond, "the simulated downstream goes down this long after the start")
+	outageFor := flag.Duration("outage-for", 500*time.Millisecond, "the simulated downstream stays down this long")
+	breakerAction := flag.String("breaker", "", "circuit breaker for the API calls: fail or requeue the calls it refuses (empty = no breaker)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs, the downstream is down from %v to %v ------------------ \n", *numApiCalls, *outageAt, *outageAt+*outageFor)
+
+	startTime := time.Now()
+	api := downstream{start: startTime, at: *outageAt, length: *outageFor}
+
+	// the breaker opens once half of the last 50 calls failed, after 200ms 5 trial calls test the downstream
+	var options []pool.Option
+	var breaker *pool.CircuitBreaker
+	if *breakerAction != "" {
+		action := pool.BreakerFail
+		if *breakerAction == "requeue" {
+			action = pool.BreakerRequeue
+		}
+		breaker = pool.NewCircuitBreaker(pool.BreakerPolicy{
+			Window:      50,
+			MinCalls:    20,
+			FailureRate: 0.5,
+			CoolDown:    200 * time.Millisecond,
+			TrialCalls:  5,
+			OnOpen:      action,
+			OnStateChange: func(change pool.BreakerChange) {
+				fmt.Printf("circuit breaker %v -> %v after %v \n", change.From, change.To, change.Time.Sub(startTime).Round(time.Millisecond))
+			},
+		})
+		options = append(options, pool.WithCircuitBreaker(breaker))
+	}
+	workers := pool.New(*numberOfWorkers, api.apiRequest, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel and count the failed calls which did call the downstream
+	called := 0
+	for result := range workers.Results() {
+		if errors.Is(result.Err, errOutage) {
+			called++
+		}
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+
+	report := workers.Report()
+	fmt.Printf("succeeded: %v, failed: %v (%v of them called the downstream) \n", report.Succeeded, report.Failed, called)
+	if breaker != nil {
+		stats := breaker.Stats()
+		if report.Requeued > 0 {
+			fmt.Printf("put back by the circuit breaker: %v times \n", report.Requeued)
+		}
+		fmt.Printf("circuit breaker: %v, calls allowed: %v, refused: %v, state changes: %v \n", stats.State, stats.Allowed, stats.Rejected, len(stats.Changes))
+	}
+}
+
+// example without a breaker: every call in the outage fails
+//
+//	% go run main.go
+//	start requesting 1000 APIs, the downstream is down from 300ms to 800ms ------------------ 
+//	total API processing time: 1.008867893s 
+//	succeeded: 500, failed: 500 (500 of them called the downstream) 
+
+// example with '-breaker fail' (the breaker opens after the first failed round and the rest fail right away)
+//
+//	% go run main.go -breaker fail
+//	start requesting 1000 APIs, the downstream is down from 300ms to 800ms ------------------ 
+//	circuit breaker closed -> open after 303ms 
+//	total API processing time: 403.465721ms 
+//	succeeded: 200, failed: 800 (124 of them called the downstream) 
+//	circuit breaker: open, calls allowed: 324, refused: 676, state changes: 1 
+
+// example with '-breaker requeue' (the refused calls wait for the breaker, only the calls made before it opened
+// and the failed trial round fail)
+// the parked calls are woken on every state change, those a half-open breaker refuses again are parked again
+//
+//	% go run main.go -breaker requeue
+//	start requesting 1000 APIs, the downstream is down from 300ms to 800ms ------------------ 
+//	circuit breaker closed -> open after 303ms 
+//	circuit breaker open -> half-open after 504ms 
+//	circuit breaker half-open -> open after 605ms 
+//	circuit breaker open -> half-open after 805ms 
+//	circuit breaker half-open -> closed after 906ms 
+//	total API processing time: 1.610878879s 
+//	succeeded: 871, failed: 129 (129 of them called the downstream) 
+//	put back by the circuit breaker: 2013 times 
+//	circuit breaker: closed, calls allowed: 1000, refused: 2013, state changes: 5 
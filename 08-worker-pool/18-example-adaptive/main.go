@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates an adaptive concurrency limit which finds the capacity of the downstream ('pool.AdaptiveLimiter')
+// -'-capacity' is how many calls the simulated downstream handles at once in 100ms
+// with more calls in flight every call slows down quadratically: twice the capacity takes 400ms per call
+// so the downstream serves fewer calls per second the more it is overloaded
+// -'-adaptive aimd' treats calls slower than 150ms as overload, '-adaptive gradient' calls 1.5 times slower than usual
+// -'-limit-csv' writes every change of the limit to plot it
+// this is in contrast to '03-example-worker-pool' where all 100 workers call the downstream whatever it can take
+
+type apiDataType struct {
+	id int
+}
+
+// 'downstream' handles 'capacity' calls at once before it slows down
+type downstream struct {
+	capacity int
+	inFlight atomic.Int64
+}
+
+func (d *downstream) apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	load := float64(d.inFlight.Add(1)) / float64(d.capacity)
+	defer d.inFlight.Add(-1)
+	latency := time.Duration(float64(100*time.Millisecond) * math.Max(1, load*load))
+
+	select {
+	case <-time.After(latency):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+// 'writeLimitCSV' writes the adaptive limit history, plot 'limit' (and 'in_flight') against 'elapsed_ms'
+func writeLimitCSV(path string, limiter *pool.AdaptiveLimiter) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := limiter.WriteHistoryCSV(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	capacity := flag.Int("capacity", 40, "calls the simulated downstream handles at once before slowing down")
+	adaptive := flag.String("adaptive", "", "adaptive concurrency limit: aimd or gradient (empty = every worker calls)")
+	limitCSV := flag.String("limit-csv", "", "write the adaptive limit over time to this CSV file")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs of a downstream with capacity %v ------------------ \n", *numApiCalls, *capacity)
+
+	startTime := time.Now()
+	api := &downstream{capacity: max(*capacity, 1)}
+
+	// the limiter starts at 10 calls in flight and moves between 1 and the number of workers
+	var options []pool.Option
+	var limiter *pool.AdaptiveLimiter
+	if *adaptive != "" {
+		algorithm := pool.AIMD
+		if *adaptive == "gradient" {
+			algorithm = pool.Gradient
+		}
+		limiter = pool.NewAdaptiveLimiter(pool.LimitPolicy{
+			Algorithm:    algorithm,
+			InitialLimit: 10,
+			MaxLimit:     *numberOfWorkers,
+			Timeout:      150 * time.Millisecond,
+		})
+		options = append(options, pool.WithAdaptiveLimit(limiter))
+	}
+	workers := pool.New(*numberOfWorkers, api.apiRequest, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel and sum up the latency of the calls
+	var latency time.Duration
+	for result := range workers.Results() {
+		latency += result.Latency
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	fmt.Printf("succeeded: %v, latency avg: %v \n", workers.Report().Succeeded, (latency / time.Duration(max(*numApiCalls, 1))).Round(time.Millisecond))
+
+	if limiter != nil {
+		history := limiter.History()
+		peak := 0
+		for _, sample := range history {
+			peak = max(peak, sample.Limit)
+		}
+		fmt.Printf("adaptive limit (%v): %v at the end, peak %v, %v changes \n", *adaptive, limiter.Limit(), peak, len(history)-1)
+		if *limitCSV != "" {
+			if err := writeLimitCSV(*limitCSV, limiter); err != nil {
+				fmt.Printf("limit history: %v \n", err)
+			}
+		}
+	}
+}
+
+// example without an adaptive limit (all 100 workers call, 2.5 times the capacity, the calls slow down to over a second)
+//
+//	% go run main.go
+//	start requesting 1000 APIs of a downstream with capacity 40 ------------------ 
+//	total API processing time: 6.107500013s 
+//	succeeded: 1000, latency avg: 1.106s 
+
+// example with '-adaptive aimd' (the slow start overshoots to 100, the slow calls cut it back near the capacity)
+//
+//	% go run main.go -adaptive aimd
+//	start requesting 1000 APIs of a downstream with capacity 40 ------------------ 
+//	total API processing time: 3.237278328s 
+//	succeeded: 1000, latency avg: 597ms 
+//	adaptive limit (aimd): 48 at the end, peak 100, 107 changes 
+
+// example with '-adaptive gradient'
+//
+//	% go run main.go -adaptive gradient
+//	start requesting 1000 APIs of a downstream with capacity 40 ------------------ 
+//	total API processing time: 3.372714894s 
+//	succeeded: 1000, latency avg: 612ms 
+//	adaptive limit (gradient): 53 at the end, peak 100, 114 changes 
+
+// example with '-limit-csv' (the history to plot)
+//
+//	% go run main.go -adaptive gradient -limit-csv limit.csv
+//	% head -4 limit.csv
+//	elapsed_ms,limit,in_flight,latency_ms
+//	0.0,10,0,0.0
+//	101.4,11,9,101.0
+//	101.5,12,9,101.0
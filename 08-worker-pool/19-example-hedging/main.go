@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates hedged calls against stragglers ('pool.HedgePolicy')
+// -'-straggler-rate' is the chance an API call is a straggler taking '-straggler-latency' instead of 100ms
+// -'-hedge p95' sends a second copy of the calls slower than 95% of the recent ones, '-hedge 150ms' after 150ms
+// -'-hedge-ratio' is the share of the calls which may be hedged
+// a hedged copy of the call is a new call, it is most likely not a straggler again
+// this is in contrast to '03-example-worker-pool' where the slowest call decides when the run ends
+
+type apiDataType struct {
+	id int
+}
+
+// 'stragglers' makes 'rate' of the calls take 'latency' instead of 100ms
+type stragglers struct {
+	rate    float64
+	latency time.Duration
+}
+
+func (s stragglers) apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	latency := 100 * time.Millisecond
+	if rand.Float64() < s.rate {
+		latency = s.latency
+	}
+	select {
+	case <-time.After(latency):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 1000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	stragglerRate := flag.Float64("straggler-rate", 0.02, "chance (0-1) an API call is a straggler")
+	stragglerLatency := flag.Duration("straggler-latency", 2*time.Second, "latency of a straggler API call")
+	hedge := flag.String("hedge", "", "hedge API calls slower than a percentile (p95) or a delay (150ms) (empty = no hedging)")
+	hedgeRatio := flag.Float64("hedge-ratio", 0.05, "share of API calls which may be hedged")
+	flag.Parse()
+
+	// the simulated calls all take 100ms, a percentile delay is at least 120ms so calls late by a millisecond are not hedged
+	var options []pool.Option
+	if *hedge != "" {
+		policy := pool.HedgePolicy{MaxRatio: *hedgeRatio, MinDelay: 120 * time.Millisecond}
+		if percentile, err := strconv.ParseFloat(strings.TrimPrefix(*hedge, "p"), 64); err == nil && strings.HasPrefix(*hedge, "p") {
+			policy.Percentile = percentile / 100
+		} else if delay, err := time.ParseDuration(*hedge); err == nil {
+			policy.Delay = delay
+		} else {
+			fmt.Printf("-hedge: %v is neither a percentile (p95) nor a duration (150ms) \n", *hedge)
+			os.Exit(1)
+		}
+		options = append(options, pool.WithHedging(policy))
+	}
+
+	fmt.Printf("start requesting %v APIs, %v%% of them stragglers taking %v ------------------ \n", *numApiCalls, *stragglerRate*100, *stragglerLatency)
+
+	startTime := time.Now()
+	api := stragglers{rate: *stragglerRate, latency: *stragglerLatency}
+	workers := pool.New(*numberOfWorkers, api.apiRequest, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel and keep the slowest call
+	var slowest time.Duration
+	for result := range workers.Results() {
+		slowest = max(slowest, result.Latency)
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	fmt.Printf("succeeded: %v, slowest call: %v \n", workers.Report().Succeeded, slowest.Round(time.Millisecond))
+	if hedges := workers.HedgeStats(); hedges.Calls > 0 {
+		fmt.Printf("hedges fired: %v of %v calls (denied: %v), won: %v, hedge delay: %v, estimated latency saved: %v \n",
+			hedges.Hedged, hedges.Calls, hedges.Denied, hedges.Won, hedges.Delay.Round(time.Millisecond), hedges.Saved.Round(time.Millisecond))
+	}
+}
+
+// example without hedging (2% of the calls take 2s instead of 100ms, the last stragglers decide the total time)
+//
+//	% go run main.go
+//	start requesting 1000 APIs, 2% of them stragglers taking 2s ------------------ 
+//	total API processing time: 3.010619275s 
+//	succeeded: 1000, slowest call: 2.203s 
+
+// example with '-hedge p95' (the calls slower than 95% of the recent ones, at least 120ms, get a second copy)
+// the first 100 calls run before there are enough samples, their stragglers still take 2s
+// the saved latency is estimated from those unhedged stragglers
+//
+//	% go run main.go -hedge p95
+//	start requesting 1000 APIs, 2% of them stragglers taking 2s ------------------ 
+//	total API processing time: 2.105894481s 
+//	succeeded: 1000, slowest call: 2.105s 
+//	hedges fired: 14 of 1000 calls (denied: 0), won: 14, hedge delay: 120ms, estimated latency saved: 12.563s 
+
+// example with '-hedge 150ms' (every straggler is hedged from the first call on)
+// hardly any straggler ran to the end, so there is little to estimate the saved latency from (compare the total time instead)
+//
+//	% go run main.go -hedge 150ms
+//	start requesting 1000 APIs, 2% of them stragglers taking 2s ------------------ 
+//	total API processing time: 1.2193134s 
+//	succeeded: 1000, slowest call: 402ms 
+//	hedges fired: 21 of 1000 calls (denied: 0), won: 21, hedge delay: 150ms, estimated latency saved: 76ms 
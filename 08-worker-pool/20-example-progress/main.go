@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates the progress of a long run ('pool.ProgressPolicy')
+// -'-progress' reports the completed calls, the rate and the time left this often
+// -on a terminal one line is redrawn, piped or redirected to a file the progress is written as structured log lines
+// this is in contrast to '03-example-worker-pool' which prints nothing until every call finished
+
+type apiDataType struct {
+	id int
+}
+
+// 'apiRequest' is a call of 50ms to 150ms
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(50*time.Millisecond + time.Duration(rand.Int63n(int64(100*time.Millisecond)))):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 5000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 200, "number of workers")
+	progress := flag.Duration("progress", 500*time.Millisecond, "report the progress this often, a line on a terminal and log lines otherwise (0 = off)")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs ------------------ \n", *numApiCalls)
+
+	startTime := time.Now()
+
+	var options []pool.Option
+	if *progress > 0 {
+		options = append(options, pool.WithProgress(pool.ProgressPolicy{Total: *numApiCalls, Interval: *progress, Output: os.Stdout}))
+	}
+	workers := pool.New(*numberOfWorkers, apiRequest, options...)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	// while loop the results channel, the progress is reported from the pool
+	for range workers.Results() {
+	}
+	workers.Wait()
+
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	fmt.Printf("succeeded: %v \n", workers.Report().Succeeded)
+}
+
+// example on a terminal (one line redrawn every 500ms, the last one stays)
+//
+//	% go run main.go
+//	start requesting 5000 APIs ------------------ 
+//	[==============================] 100.0%  5000/5000  1924/s  eta done  in flight 0  queued 0
+//	total API processing time: 2.600818855s 
+//	succeeded: 5000 
+
+// example with the output piped (or redirected to a file), the progress is written as structured log lines instead
+//
+//	% go run main.go | cat
+//	start requesting 5000 APIs ------------------ 
+//	time=2026-10-18T01:56:49.843Z level=INFO msg=progress completed=911 total=5000 percent=18.2 rate=1822 eta=2.2s in_flight=200 queued=200 elapsed=500ms
+//	time=2026-10-18T01:56:50.343Z level=INFO msg=progress completed=1906 total=5000 percent=38.1 rate=1906 eta=1.6s in_flight=200 queued=200 elapsed=1s
+//	time=2026-10-18T01:56:50.842Z level=INFO msg=progress completed=2895 total=5000 percent=57.9 rate=1930 eta=1.1s in_flight=200 queued=200 elapsed=1.5s
+//	time=2026-10-18T01:56:51.343Z level=INFO msg=progress completed=3880 total=5000 percent=77.6 rate=1940 eta=600ms in_flight=199 queued=200 elapsed=2s
+//	time=2026-10-18T01:56:51.842Z level=INFO msg=progress completed=4882 total=5000 percent=97.6 rate=1953 eta=100ms in_flight=118 queued=0 elapsed=2.5s
+//	time=2026-10-18T01:56:51.946Z level=INFO msg="progress done" completed=5000 total=5000 percent=100.0 rate=1920 eta=0s in_flight=0 queued=0 elapsed=2.604s
+//	total API processing time: 2.607306872s 
+//	succeeded: 5000 
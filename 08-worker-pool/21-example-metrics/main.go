@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// example demonstrates the pool metrics in Prometheus format ('Pool.MetricsHandler')
+// -'-metrics-addr' serves '/metrics' while the pool runs, scrape it with a local Prometheus or curl
+// -'-linger' keeps serving after the run, so the final counts can be scraped too
+// this is in contrast to '03-example-worker-pool' which only reports at the end of the run
+
+type apiDataType struct {
+	id int
+}
+
+// 'apiRequest' is a call of 50ms to 1s which fails 1% of the time
+func apiRequest(ctx context.Context, data apiDataType) (int, error) {
+	select {
+	case <-time.After(50*time.Millisecond + time.Duration(rand.Int63n(int64(950*time.Millisecond)))):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	if rand.Float64() < 0.01 {
+		return 0, fmt.Errorf("api %v: simulated failure", data.id)
+	}
+	return data.id, nil
+}
+
+func main() {
+
+	numApiCalls := flag.Int("calls", 3000, "number of API calls")
+	numberOfWorkers := flag.Int("workers", 100, "number of workers")
+	metricsAddr := flag.String("metrics-addr", "localhost:2112", "serve Prometheus metrics on this address")
+	linger := flag.Duration("linger", 0, "keep serving the metrics this long after the run")
+	flag.Parse()
+
+	fmt.Printf("start requesting %v APIs ------------------ \n", *numApiCalls)
+
+	startTime := time.Now()
+	workers := pool.New(*numberOfWorkers, apiRequest)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", workers.MetricsHandler())
+	go func() {
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			fmt.Printf("metrics endpoint: %v \n", err)
+		}
+	}()
+	fmt.Printf("serving metrics on http://%v/metrics \n", *metricsAddr)
+
+	go func() {
+		defer workers.Close()
+		for i := 0; i < *numApiCalls; i++ {
+			workers.Submit(apiDataType{id: i})
+		}
+	}()
+
+	for range workers.Results() {
+	}
+	workers.Wait()
+
+	report := workers.Report()
+	fmt.Printf("total API processing time: %v \n", time.Since(startTime))
+	fmt.Printf("succeeded: %v, failed: %v \n", report.Succeeded, report.Failed)
+	time.Sleep(*linger)
+}
+
+// example scraped 3 seconds into the run
+//
+//	% go run main.go
+//	start requesting 3000 APIs ------------------ 
+//	serving metrics on http://localhost:2112/metrics 
+//	total API processing time: 16.469594773s 
+//	succeeded: 2968, failed: 32 
+//
+//	% curl -s localhost:2112/metrics
+//	# HELP worker_pool_queue_depth Tasks waiting in the queue.
+//	# TYPE worker_pool_queue_depth gauge
+//	worker_pool_queue_depth 100
+//	# HELP worker_pool_workers Workers by state.
+//	# TYPE worker_pool_workers gauge
+//	worker_pool_workers{state="busy"} 100
+//	worker_pool_workers{state="idle"} 0
+//	# HELP worker_pool_tasks_total Tasks processed by outcome.
+//	# TYPE worker_pool_tasks_total counter
+//	worker_pool_tasks_total{status="succeeded"} 331
+//	worker_pool_tasks_total{status="failed"} 1
+//	worker_pool_tasks_total{status="cancelled"} 0
+//	worker_pool_tasks_total{status="not_started"} 0
+//	worker_pool_tasks_total{status="quarantined"} 0
+//	...
+//	# HELP worker_pool_task_latency_seconds Time from submit to result.
+//	# TYPE worker_pool_task_latency_seconds histogram
+//	worker_pool_task_latency_seconds_bucket{le="0.1"} 6
+//	worker_pool_task_latency_seconds_bucket{le="0.25"} 22
+//	worker_pool_task_latency_seconds_bucket{le="0.5"} 65
+//	worker_pool_task_latency_seconds_bucket{le="1"} 231
+//	worker_pool_task_latency_seconds_bucket{le="2.5"} 332
+//	worker_pool_task_latency_seconds_bucket{le="5"} 332
+//	worker_pool_task_latency_seconds_bucket{le="10"} 332
+//	worker_pool_task_latency_seconds_bucket{le="+Inf"} 332
+//	worker_pool_task_latency_seconds_sum 272.873349121
+//	worker_pool_task_latency_seconds_count 332
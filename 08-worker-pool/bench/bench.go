@@ -0,0 +1,153 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/internal/goroutines"
+)
+
+// 'Measurement' is the outcome of one strategy over one workload
+// task latency is measured from the start of the run (every task is pre-loaded like the examples) to the end of its call
+// so it includes the time a task waited for its turn, not only the simulated 'Latency'
+type Measurement struct {
+	Strategy       string        `json:"strategy"`
+	Tasks          int           `json:"tasks"`
+	Latency        time.Duration `json:"latency_ns"`
+	Workers        int           `json:"workers"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+	Throughput     float64       `json:"throughput_per_sec"`
+	P50            time.Duration `json:"p50_ns"`
+	P95            time.Duration `json:"p95_ns"`
+	P99            time.Duration `json:"p99_ns"`
+	PeakGoroutines int           `json:"peak_goroutines"`
+	Allocs         uint64        `json:"allocs"`
+	AllocBytes     uint64        `json:"alloc_bytes"`
+}
+
+// 'Run' runs 'strategy' over 'workload' once and measures it
+func Run(strategy Strategy, workload Workload) Measurement {
+	latencies := make([]time.Duration, workload.Tasks)
+	var mu sync.Mutex
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	sampler := goroutines.Sample(time.Millisecond)
+	startTime := time.Now()
+
+	// 'Workers' is reported only for the strategies that use it
+	if !strategy.Pooled {
+		workload.Workers = 0
+	}
+
+	strategy.Run(workload, func(ids ...int) {
+		time.Sleep(workload.Latency)
+		latency := time.Since(startTime)
+		mu.Lock()
+		for _, id := range ids {
+			latencies[id] = latency
+		}
+		mu.Unlock()
+	})
+
+	elapsed := time.Since(startTime)
+	peak := sampler.Stop()
+	runtime.ReadMemStats(&after)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return Measurement{
+		Strategy:       strategy.Name,
+		Tasks:          workload.Tasks,
+		Latency:        workload.Latency,
+		Workers:        workload.Workers,
+		Elapsed:        elapsed,
+		Throughput:     float64(workload.Tasks) / elapsed.Seconds(),
+		P50:            Percentile(latencies, 50),
+		P95:            Percentile(latencies, 95),
+		P99:            Percentile(latencies, 99),
+		PeakGoroutines: peak,
+		Allocs:         after.Mallocs - before.Mallocs,
+		AllocBytes:     after.TotalAlloc - before.TotalAlloc,
+	}
+}
+
+// 'Matrix' is every combination of task counts, latencies and worker counts
+// 'synch' and 'asynch' ignore 'Workers' so 'RunMatrix' runs them once per task count and latency
+func Matrix(tasks []int, latencies []time.Duration, workers []int) []Workload {
+	var workloads []Workload
+	for _, t := range tasks {
+		for _, l := range latencies {
+			for _, w := range workers {
+				workloads = append(workloads, Workload{Tasks: t, Latency: l, Workers: w})
+			}
+		}
+	}
+	return workloads
+}
+
+// 'RunMatrix' runs each strategy over each workload
+func RunMatrix(strategies []Strategy, workloads []Workload) []Measurement {
+	var measurements []Measurement
+	seen := make(map[string]bool)
+	for _, workload := range workloads {
+		for _, strategy := range strategies {
+			if !strategy.Pooled {
+				key := fmt.Sprint(strategy.Name, workload.Tasks, workload.Latency)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			measurements = append(measurements, Run(strategy, workload))
+		}
+	}
+	return measurements
+}
+
+// 'Percentile' of already sorted durations (nearest rank)
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// 'WriteTable' writes measurements as an aligned text table
+func WriteTable(w io.Writer, measurements []Measurement) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "strategy\ttasks\tlatency\tworkers\telapsed\ttasks/sec\tp50\tp95\tp99\tpeak goroutines\tallocs\talloc bytes\t")
+	for _, m := range measurements {
+		workers := "-"
+		if m.Workers > 0 {
+			workers = fmt.Sprint(m.Workers)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%.1f\t%v\t%v\t%v\t%v\t%v\t%v\t\n",
+			m.Strategy, m.Tasks, m.Latency, workers, m.Elapsed.Round(time.Millisecond), m.Throughput,
+			m.P50.Round(time.Millisecond), m.P95.Round(time.Millisecond), m.P99.Round(time.Millisecond),
+			m.PeakGoroutines, m.Allocs, m.AllocBytes)
+	}
+	return tw.Flush()
+}
+
+// 'WriteJSON' writes measurements as an indented JSON array
+func WriteJSON(w io.Writer, measurements []Measurement) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(measurements)
+}
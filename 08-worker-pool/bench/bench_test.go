@@ -0,0 +1,71 @@
+package bench
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// go test -bench . -benchtime 3x ./08-worker-pool/bench
+// each iteration runs a whole workload, p50/p95/p99 and peak goroutines are reported per iteration
+
+func benchmarkStrategy(b *testing.B, strategy Strategy, workloads []Workload) {
+	for _, workload := range workloads {
+		name := fmt.Sprintf("tasks=%v/latency=%v/workers=%v", workload.Tasks, workload.Latency, workload.Workers)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var p50, p95, p99 time.Duration
+			var peak int
+			for i := 0; i < b.N; i++ {
+				m := Run(strategy, workload)
+				p50 += m.P50
+				p95 += m.P95
+				p99 += m.P99
+				peak = max(peak, m.PeakGoroutines)
+			}
+			b.ReportMetric(float64(workload.Tasks*b.N)/b.Elapsed().Seconds(), "tasks/s")
+			b.ReportMetric(float64(p50.Milliseconds())/float64(b.N), "p50-ms")
+			b.ReportMetric(float64(p95.Milliseconds())/float64(b.N), "p95-ms")
+			b.ReportMetric(float64(p99.Milliseconds())/float64(b.N), "p99-ms")
+			b.ReportMetric(float64(peak), "peak-goroutines")
+		})
+	}
+}
+
+func BenchmarkSynch(b *testing.B) {
+	benchmarkStrategy(b, Strategies[0], Matrix([]int{10, 100}, []time.Duration{time.Millisecond}, []int{1}))
+}
+
+func BenchmarkAsynch(b *testing.B) {
+	benchmarkStrategy(b, Strategies[1], Matrix([]int{100, 1000, 10000}, []time.Duration{time.Millisecond, 10 * time.Millisecond}, []int{1}))
+}
+
+func BenchmarkWorkerPool(b *testing.B) {
+	benchmarkStrategy(b, Strategies[2], Matrix([]int{100, 1000}, []time.Duration{time.Millisecond, 10 * time.Millisecond}, []int{10, 100}))
+}
+
+// the shared channel against work stealing, from no work at all (only scheduling) to 1ms tasks
+//
+//	% go test -bench Scheduler -benchtime 3x ./08-worker-pool/bench
+func BenchmarkScheduler(b *testing.B) {
+	workloads := Matrix([]int{20000}, []time.Duration{0, 10 * time.Microsecond, time.Millisecond}, []int{8, 64})
+	for _, name := range []string{"worker-pool", "work-stealing"} {
+		strategy, _ := Lookup(name)
+		b.Run(name, func(b *testing.B) {
+			benchmarkStrategy(b, strategy, workloads)
+		})
+	}
+}
+
+// one API call per task against one call per batch of up to 10 tasks, a call costs the same latency either way
+//
+//	% go test -bench Batch -benchtime 3x ./08-worker-pool/bench
+func BenchmarkBatch(b *testing.B) {
+	workloads := Matrix([]int{1000}, []time.Duration{time.Millisecond, 10 * time.Millisecond}, []int{10, 100})
+	for _, name := range []string{"worker-pool", "batched"} {
+		strategy, _ := Lookup(name)
+		b.Run(name, func(b *testing.B) {
+			benchmarkStrategy(b, strategy, workloads)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// package 'bench' runs the three '08-worker-pool' strategies side by side over a matrix of workloads
+// -'synch':       '01-example-synch', every API call one after another
+// -'asynch':      '02-example-asynch', one goroutine per API call
+// -'worker-pool': '03-example-worker-pool', 'Workers' goroutines sharing a buffered channel ('pool.Pool')
+// -'work-stealing': the same 'pool.Pool' with 'pool.WithScheduler(pool.WorkStealing)', one deque per worker
+// -'batched':     the same 'pool.Pool' made with 'pool.NewBatch', each worker sends up to 10 ids per API call
+
+// 'Workload' is one cell of the matrix
+// 'Latency' is how long each simulated API call sleeps, 'Workers' is only used by 'worker-pool'
+type Workload struct {
+	Tasks   int
+	Latency time.Duration
+	Workers int
+}
+
+// 'Strategy' processes every task of a workload, calling 'apiRequest(id)' once per task
+// or 'apiRequest(ids...)' once per batch of tasks, a call costs one 'Latency' however many ids it carries
+// 'Pooled' strategies use 'Workload.Workers', the others ignore it
+type Strategy struct {
+	Name   string
+	Run    func(w Workload, apiRequest func(ids ...int))
+	Pooled bool
+}
+
+// 'Strategies' are the three '08-worker-pool' examples in order, then the work-stealing and the batch pool
+var Strategies = []Strategy{
+	{Name: "synch", Run: synch},
+	{Name: "asynch", Run: asynch},
+	{Name: "worker-pool", Run: workerPool(), Pooled: true},
+	{Name: "work-stealing", Run: workerPool(pool.WithScheduler(pool.WorkStealing)), Pooled: true},
+	{Name: "batched", Run: batched(pool.BatchPolicy{MaxSize: 10, MaxWait: time.Millisecond}), Pooled: true},
+}
+
+// 'Lookup' returns the strategy called 'name'
+func Lookup(name string) (Strategy, bool) {
+	for _, strategy := range Strategies {
+		if strategy.Name == name {
+			return strategy, true
+		}
+	}
+	return Strategy{}, false
+}
+
+// '01-example-synch' 'work()'
+func synch(w Workload, apiRequest func(ids ...int)) {
+	for i := 0; i < w.Tasks; i++ {
+		apiRequest(i)
+	}
+}
+
+// '02-example-asynch' 'work()' (a reading goroutine starts one 'fetch' goroutine per call)
+func asynch(w Workload, apiRequest func(ids ...int)) {
+	var wg sync.WaitGroup
+
+	bufferedChannel := make(chan int, w.Tasks)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			id, open := <-bufferedChannel
+			if !open {
+				break
+			}
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				apiRequest(id)
+			}(id)
+		}
+	}()
+
+	for i := 0; i < w.Tasks; i++ {
+		bufferedChannel <- i
+	}
+	close(bufferedChannel)
+
+	wg.Wait()
+}
+
+// '03-example-worker-pool' 'workerPool()' on 'pool.Pool' configured with 'options'
+func workerPool(options ...pool.Option) func(w Workload, apiRequest func(ids ...int)) {
+	return func(w Workload, apiRequest func(ids ...int)) {
+		workers := pool.New(w.Workers, func(ctx context.Context, id int) (struct{}, error) {
+			apiRequest(id)
+			return struct{}{}, nil
+		}, options...)
+
+		go func() {
+			defer workers.Close()
+			for i := 0; i < w.Tasks; i++ {
+				workers.Submit(i)
+			}
+		}()
+
+		for range workers.Results() {
+		}
+		workers.Wait()
+	}
+}
+
+// 'workerPool' with 'pool.NewBatch': a worker collects up to 'policy.MaxSize' ids and calls 'apiRequest' once for all of them
+func batched(policy pool.BatchPolicy) func(w Workload, apiRequest func(ids ...int)) {
+	return func(w Workload, apiRequest func(ids ...int)) {
+		workers := pool.NewBatch(w.Workers, func(ctx context.Context, ids []int) ([]struct{}, error) {
+			apiRequest(ids...)
+			return make([]struct{}, len(ids)), nil
+		}, policy)
+
+		go func() {
+			defer workers.Close()
+			for i := 0; i < w.Tasks; i++ {
+				workers.Submit(i)
+			}
+		}()
+
+		for range workers.Results() {
+		}
+		workers.Wait()
+	}
+}
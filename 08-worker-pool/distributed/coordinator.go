@@ -0,0 +1,476 @@
+package distributed
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// package 'distributed' spreads the '08-worker-pool' design over several processes
+// a 'Coordinator' owns the task queue, remote worker processes lease tasks over TCP ('net/rpc')
+// -a worker 'Register's, then 'Lease's tasks, runs them and reports each one with 'Complete'
+// -while it holds leases the worker sends a 'Heartbeat' every 'HeartbeatInterval' which extends them
+// -a lease not extended within 'LeaseTTL' expires (the worker died or hung) and the task is dispatched again
+// a task is finished by its first successful 'Complete', even a late one from an expired lease (the re-dispatched
+// copy is dropped then), a failed 'Complete' only counts from the worker holding the lease
+// once every task finished after 'Close' each worker gets 'Done' from its next 'Lease', 'Wait' returns once all of
+// them heard it (a worker not heard from within 'LeaseTTL' is taken for dead)
+
+// 'Task' is one unit of work, 'Payload' is opaque to the coordinator (the example uses JSON)
+type Task struct {
+	ID       uint64
+	Payload  []byte
+	Attempts int
+}
+
+// 'TaskResult' is a finished task as reported by the worker that completed it
+type TaskResult struct {
+	Task   Task
+	Worker string
+	Result []byte
+	Err    string
+}
+
+// 'Config' configures a coordinator
+// 'LeaseTTL' is how long a lease lives without a heartbeat, 'HeartbeatInterval' is sent to workers on 'Register'
+// 'MaxAttempts' gives up on a task whose lease expired that many times (0 = never give up)
+type Config struct {
+	LeaseTTL          time.Duration
+	HeartbeatInterval time.Duration
+	MaxAttempts       int
+	Logger            *log.Logger
+}
+
+type lease struct {
+	task    Task
+	worker  string
+	expires time.Time
+}
+
+// 'member' is a registered worker, 'seen' is its last call and 'told' is set once it got 'Done'
+type member struct {
+	seen time.Time
+	told bool
+}
+
+// 'Coordinator' is the task queue shared by every worker process
+type Coordinator struct {
+	config Config
+
+	mu      sync.Mutex
+	queue   []Task
+	leases  map[uint64]*lease
+	nextID  uint64
+	workers int
+	members map[string]*member
+	closed  bool
+	wake    chan struct{}
+	// 'pending' are finished tasks 'deliver' has not sent on 'results' yet, 'delivered' wakes it
+	pending   []TaskResult
+	delivered chan struct{}
+
+	results  chan TaskResult
+	done     chan struct{}
+	doneOnce sync.Once
+	listener net.Listener
+}
+
+// 'NewCoordinator' returns a coordinator, call 'Listen' to accept worker connections
+func NewCoordinator(config Config) *Coordinator {
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = 3 * time.Second
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = config.LeaseTTL / 3
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+	c := &Coordinator{
+		config:    config,
+		leases:    make(map[uint64]*lease),
+		nextID:    1,
+		members:   make(map[string]*member),
+		wake:      make(chan struct{}),
+		delivered: make(chan struct{}, 1),
+		results:   make(chan TaskResult, 1024),
+		done:      make(chan struct{}),
+	}
+	go c.deliver()
+	return c
+}
+
+// 'Listen' serves the 'Coordinator' RPC service on 'addr' ("localhost:0" picks a free port, see 'Addr')
+func (c *Coordinator) Listen(addr string) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", &service{c: c}); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	c.listener = listener
+
+	// accept loop ('server.Accept' logs an error when the listener is closed by 'Wait')
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+	go c.expireLoop()
+	return nil
+}
+
+// 'Addr' is the address the coordinator listens on
+func (c *Coordinator) Addr() string {
+	return c.listener.Addr().String()
+}
+
+// 'Submit' queues a task and returns its id
+func (c *Coordinator) Submit(payload []byte) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, errors.New("distributed: coordinator closed")
+	}
+	id := c.nextID
+	c.nextID++
+	c.queue = append(c.queue, Task{ID: id, Payload: payload})
+	c.signal()
+	return id, nil
+}
+
+// 'Close' stops accepting tasks, workers are told they are done once every task finished
+func (c *Coordinator) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.checkDone()
+}
+
+// 'Results' is one 'TaskResult' per finished task, closed once every task finished after 'Close'
+func (c *Coordinator) Results() <-chan TaskResult {
+	return c.results
+}
+
+// 'Wait' blocks until every task finished after 'Close' and every live worker got 'Done', then stops listening
+func (c *Coordinator) Wait() {
+	<-c.done
+	if c.listener == nil {
+		return
+	}
+	for {
+		c.mu.Lock()
+		waiting := c.untold(time.Now())
+		wake := c.wake
+		c.mu.Unlock()
+		if waiting == 0 {
+			break
+		}
+		// a worker which died is only noticed by its silence, look again after a heartbeat
+		timer := time.NewTimer(c.config.HeartbeatInterval)
+		select {
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	c.listener.Close()
+}
+
+// 'untold' is the number of workers which did not get 'Done' yet and were heard from within 'LeaseTTL'
+// the caller holds 'c.mu'
+func (c *Coordinator) untold(now time.Time) int {
+	n := 0
+	for _, m := range c.members {
+		if !m.told && now.Sub(m.seen) < c.config.LeaseTTL {
+			n++
+		}
+	}
+	return n
+}
+
+// 'touch' records a call of 'workerID', the caller holds 'c.mu'
+func (c *Coordinator) touch(workerID string) {
+	if m, ok := c.members[workerID]; ok {
+		m.seen = time.Now()
+	}
+}
+
+// 'Stats' is the number of queued tasks, leased tasks and registered workers
+func (c *Coordinator) Stats() (queued, leased, workers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue), len(c.leases), c.workers
+}
+
+// 'signal' wakes every 'Lease' call waiting for a task, the caller holds 'c.mu'
+func (c *Coordinator) signal() {
+	close(c.wake)
+	c.wake = make(chan struct{})
+}
+
+// 'checkDone' closes 'done' once the coordinator is closed and idle ('deliver' then closes 'results')
+// the caller holds 'c.mu'
+func (c *Coordinator) checkDone() {
+	if c.closed && len(c.queue) == 0 && len(c.leases) == 0 {
+		c.doneOnce.Do(func() {
+			close(c.done)
+			c.signal()
+		})
+	}
+}
+
+// 'expireLoop' re-dispatches the tasks of expired leases
+func (c *Coordinator) expireLoop() {
+	ticker := time.NewTicker(c.config.LeaseTTL / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for id, l := range c.leases {
+				if now.Before(l.expires) {
+					continue
+				}
+				delete(c.leases, id)
+				if c.config.MaxAttempts > 0 && l.task.Attempts >= c.config.MaxAttempts {
+					c.config.Logger.Printf("coordinator: task %v lease expired on %v, giving up after %v attempts", id, l.worker, l.task.Attempts)
+					c.finish(TaskResult{Task: l.task, Worker: l.worker, Err: "lease expired too many times"})
+					continue
+				}
+				c.config.Logger.Printf("coordinator: task %v lease expired on %v, re-dispatching", id, l.worker)
+				c.queue = append([]Task{l.task}, c.queue...)
+			}
+			c.signal()
+			c.checkDone()
+			c.mu.Unlock()
+		}
+	}
+}
+
+// 'finish' hands a finished task to 'deliver', the caller holds 'c.mu'
+func (c *Coordinator) finish(result TaskResult) {
+	c.pending = append(c.pending, result)
+	select {
+	case c.delivered <- struct{}{}:
+	default:
+	}
+}
+
+// 'deliver' sends the finished tasks on 'results' in the order they finished, without holding 'c.mu'
+// so a caller slow to read 'Results' holds up no RPC (the tasks wait in 'pending'), 'results' is closed after the last one
+func (c *Coordinator) deliver() {
+	defer close(c.results)
+	for {
+		c.mu.Lock()
+		pending := c.pending
+		c.pending = nil
+		c.mu.Unlock()
+
+		for _, result := range pending {
+			c.results <- result
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		select {
+		case <-c.delivered:
+		case <-c.done:
+			// 'done' is closed after the last 'finish', anything left is in 'pending' now
+			c.mu.Lock()
+			pending = c.pending
+			c.pending = nil
+			c.mu.Unlock()
+			for _, result := range pending {
+				c.results <- result
+			}
+			return
+		}
+	}
+}
+
+// 'service' holds the exported RPC methods ('net/rpc' method shape: 'func (t *T) Method(args A, reply *R) error')
+type service struct {
+	c *Coordinator
+}
+
+type RegisterArgs struct {
+	Name string
+}
+
+type RegisterReply struct {
+	WorkerID          string
+	LeaseTTL          time.Duration
+	HeartbeatInterval time.Duration
+}
+
+func (s *service) Register(args RegisterArgs, reply *RegisterReply) error {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workers++
+	reply.WorkerID = fmt.Sprintf("%v-%v", args.Name, c.workers)
+	c.members[reply.WorkerID] = &member{seen: time.Now()}
+	reply.LeaseTTL = c.config.LeaseTTL
+	reply.HeartbeatInterval = c.config.HeartbeatInterval
+	c.config.Logger.Printf("coordinator: worker %v registered", reply.WorkerID)
+	return nil
+}
+
+// 'LeaseArgs' asks for up to 'Max' tasks, waiting up to 'Wait' when the queue is empty (long poll)
+type LeaseArgs struct {
+	WorkerID string
+	Max      int
+	Wait     time.Duration
+}
+
+// 'LeaseReply' 'Done' is set once the coordinator is closed and every task finished
+type LeaseReply struct {
+	Tasks []Task
+	Done  bool
+}
+
+func (s *service) Lease(args LeaseArgs, reply *LeaseReply) error {
+	c := s.c
+	deadline := time.Now().Add(args.Wait)
+
+	for {
+		c.mu.Lock()
+		c.touch(args.WorkerID)
+		if n := min(args.Max, len(c.queue)); n > 0 {
+			now := time.Now()
+			for _, task := range c.queue[:n] {
+				task.Attempts++
+				c.leases[task.ID] = &lease{task: task, worker: args.WorkerID, expires: now.Add(c.config.LeaseTTL)}
+				reply.Tasks = append(reply.Tasks, task)
+			}
+			c.queue = c.queue[n:]
+			c.mu.Unlock()
+			return nil
+		}
+
+		select {
+		case <-c.done:
+			if m, ok := c.members[args.WorkerID]; ok {
+				m.told = true
+			}
+			// 'Wait' may be waiting for this worker
+			c.signal()
+			c.mu.Unlock()
+			reply.Done = true
+			return nil
+		default:
+		}
+
+		wake := c.wake
+		c.mu.Unlock()
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+type HeartbeatArgs struct {
+	WorkerID string
+	TaskIDs  []uint64
+}
+
+// 'HeartbeatReply' 'Lost' lists tasks the worker no longer holds (expired and re-dispatched or finished)
+type HeartbeatReply struct {
+	Lost []uint64
+}
+
+func (s *service) Heartbeat(args HeartbeatArgs, reply *HeartbeatReply) error {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.touch(args.WorkerID)
+	expires := time.Now().Add(c.config.LeaseTTL)
+	for _, id := range args.TaskIDs {
+		l, ok := c.leases[id]
+		if !ok || l.worker != args.WorkerID {
+			reply.Lost = append(reply.Lost, id)
+			continue
+		}
+		l.expires = expires
+	}
+	return nil
+}
+
+type CompleteArgs struct {
+	WorkerID string
+	TaskID   uint64
+	Result   []byte
+	Err      string
+}
+
+// 'CompleteReply' 'Accepted' is false for a duplicate or late completion
+type CompleteReply struct {
+	Accepted bool
+}
+
+func (s *service) Complete(args CompleteArgs, reply *CompleteReply) error {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.touch(args.WorkerID)
+
+	// a task which is neither leased nor queued finished already (or never existed), see below
+	var task Task
+	if l, ok := c.leases[args.TaskID]; ok {
+		// the task was re-dispatched to another worker, only a successful late result is taken
+		if l.worker != args.WorkerID && args.Err != "" {
+			return nil
+		}
+		task = l.task
+		delete(c.leases, args.TaskID)
+	} else {
+		// the lease expired but the task was not finished yet: take a successful late result and drop the queued copy
+		if args.Err != "" {
+			return nil
+		}
+		found := false
+		for i, queued := range c.queue {
+			if queued.ID == args.TaskID {
+				task = queued
+				c.queue = append(c.queue[:i], c.queue[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	c.finish(TaskResult{Task: task, Worker: args.WorkerID, Result: args.Result, Err: args.Err})
+	reply.Accepted = true
+	c.checkDone()
+	return nil
+}
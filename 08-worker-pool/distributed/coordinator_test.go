@@ -0,0 +1,136 @@
+package distributed
+
+import (
+	"context"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// go test -race ./08-worker-pool/distributed
+
+// a coordinator and 3 workers on 127.0.0.1, the first worker is killed while it holds leases
+// its tasks are dispatched again after 'LeaseTTL', every task is reported exactly once
+func TestCoordinatorWorkerKilled(t *testing.T) {
+	const numTasks = 200
+
+	logger := log.New(io.Discard, "", 0)
+	c := NewCoordinator(Config{LeaseTTL: 200 * time.Millisecond, HeartbeatInterval: 50 * time.Millisecond, Logger: logger})
+	if err := c.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler returns the task number, 'started' counts the tasks each worker started
+	// after its 10th task the first worker holds every task it starts until it is killed, so its leases must expire
+	var started [3]atomic.Int64
+	var mu sync.Mutex
+	held := make(map[string]bool)
+	holding := make(chan struct{})
+	var once sync.Once
+	handler := func(worker int) Handler {
+		return func(ctx context.Context, payload []byte) ([]byte, error) {
+			if started[worker].Add(1) > 10 && worker == 0 {
+				mu.Lock()
+				held[string(payload)] = true
+				mu.Unlock()
+				once.Do(func() { close(holding) })
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			select {
+			case <-time.After(5 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return payload, nil
+		}
+	}
+
+	// killing a worker cancels its context: its tasks in flight are not reported and its heartbeats stop
+	killed, kill := context.WithCancel(context.Background())
+	defer kill()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = killed
+		}
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			config := WorkerConfig{Name: "w" + strconv.Itoa(i), Concurrency: 4, Logger: logger}
+			if err := RunWorker(ctx, c.Addr(), config, handler(i)); err != nil && ctx.Err() == nil {
+				t.Errorf("worker %v: %v", i, err)
+			}
+		}(i, ctx)
+	}
+
+	for i := 0; i < numTasks; i++ {
+		if _, err := c.Submit([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close()
+
+	go func() {
+		<-holding
+		kill()
+	}()
+
+	finished := make(chan map[string][]TaskResult)
+	go func() {
+		completed := make(map[string][]TaskResult)
+		for result := range c.Results() {
+			if result.Err != "" {
+				t.Errorf("task %v failed on %v: %v", result.Task.ID, result.Worker, result.Err)
+			}
+			completed[string(result.Result)] = append(completed[string(result.Result)], result)
+		}
+		finished <- completed
+	}()
+
+	var completed map[string][]TaskResult
+	select {
+	case completed = <-finished:
+	case <-time.After(20 * time.Second):
+		queued, leased, _ := c.Stats()
+		t.Fatalf("the tasks never finished, %v queued and %v leased", queued, leased)
+	}
+	for i := 0; i < numTasks; i++ {
+		if n := len(completed[strconv.Itoa(i)]); n != 1 {
+			t.Errorf("task %v completed %v times, want 1", i, n)
+		}
+	}
+
+	// 'Wait' returns once the 2 live workers heard they are done, it does not wait for the dead one
+	waited := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return")
+	}
+	wg.Wait()
+
+	// every task held by the killed worker was dispatched again and completed by another worker
+	mu.Lock()
+	defer mu.Unlock()
+	if len(held) == 0 {
+		t.Fatal("the killed worker held no task")
+	}
+	for task := range held {
+		results := completed[task]
+		if len(results) == 1 && (strings.HasPrefix(results[0].Worker, "w0-") || results[0].Task.Attempts < 2) {
+			t.Errorf("task %v held by the killed worker completed on %v after %v attempts", task, results[0].Worker, results[0].Task.Attempts)
+		}
+	}
+}
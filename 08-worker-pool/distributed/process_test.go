@@ -0,0 +1,177 @@
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the test binary runs again as a worker process when 'workerAddrEnv' is set (see 'startWorker')
+const (
+	workerAddrEnv = "DISTRIBUTED_TEST_WORKER_ADDR"
+	workerNameEnv = "DISTRIBUTED_TEST_WORKER_NAME"
+	workerHangEnv = "DISTRIBUTED_TEST_WORKER_HANG"
+)
+
+func TestMain(m *testing.M) {
+	if addr := os.Getenv(workerAddrEnv); addr != "" {
+		os.Exit(runTestWorker(addr))
+	}
+	os.Exit(m.Run())
+}
+
+// 'runTestWorker' is the worker process: it returns the task number after 5ms
+// a hanging worker prints the number of every task it starts and never finishes one
+func runTestWorker(addr string) int {
+	hang := os.Getenv(workerHangEnv) != ""
+	handler := func(ctx context.Context, payload []byte) ([]byte, error) {
+		if hang {
+			fmt.Printf("%s\n", payload)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		select {
+		case <-time.After(5 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return payload, nil
+	}
+
+	config := WorkerConfig{Name: os.Getenv(workerNameEnv), Concurrency: 2, Logger: log.New(io.Discard, "", 0)}
+	if err := RunWorker(context.Background(), addr, config, handler); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// 'startWorker' runs the test binary as a worker process connected to 'addr'
+func startWorker(t *testing.T, addr, name string, hang bool) (*exec.Cmd, io.Reader) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), workerAddrEnv+"="+addr, workerNameEnv+"="+name)
+	if hang {
+		cmd.Env = append(cmd.Env, workerHangEnv+"=1")
+	}
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, stdout
+}
+
+// a coordinator and 3 worker processes on 127.0.0.1, the first one is killed ('SIGKILL') while it holds leases
+// its TCP connection drops and its heartbeats stop, its tasks are dispatched again after 'LeaseTTL'
+func TestCoordinatorWorkerProcesses(t *testing.T) {
+	const numTasks = 100
+
+	logger := log.New(io.Discard, "", 0)
+	c := NewCoordinator(Config{LeaseTTL: 300 * time.Millisecond, HeartbeatInterval: 50 * time.Millisecond, Logger: logger})
+	if err := c.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < numTasks; i++ {
+		if _, err := c.Submit([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close()
+
+	// the hanging worker starts first so it leases tasks before the others take them all
+	hanging, stdout := startWorker(t, c.Addr(), "w0", true)
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	held := make(map[string]bool)
+	select {
+	case line := <-lines:
+		held[line] = true
+	case <-time.After(10 * time.Second):
+		hanging.Process.Kill()
+		t.Fatal("the hanging worker started no task")
+	}
+
+	var workers []*exec.Cmd
+	for i := 1; i < 3; i++ {
+		cmd, _ := startWorker(t, c.Addr(), "w"+strconv.Itoa(i), false)
+		workers = append(workers, cmd)
+	}
+
+	// the hanging worker keeps its leases alive until it is killed
+	results := make(chan TaskResult, numTasks)
+	go func() {
+		for result := range c.Results() {
+			results <- result
+		}
+		close(results)
+	}()
+	completed := make(map[string][]TaskResult)
+	for len(completed) < 10 {
+		result := <-results
+		completed[string(result.Result)] = append(completed[string(result.Result)], result)
+	}
+	if err := hanging.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	for line := range lines {
+		held[line] = true
+	}
+	if err := hanging.Wait(); err == nil || !strings.Contains(err.Error(), "killed") {
+		t.Errorf("the hanging worker exited with %v, want killed", err)
+	}
+
+	timeout := time.After(20 * time.Second)
+	for done := false; !done; {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				done = true
+				break
+			}
+			if result.Err != "" {
+				t.Errorf("task %v failed on %v: %v", result.Task.ID, result.Worker, result.Err)
+			}
+			completed[string(result.Result)] = append(completed[string(result.Result)], result)
+		case <-timeout:
+			queued, leased, _ := c.Stats()
+			t.Fatalf("the tasks never finished, %v queued and %v leased", queued, leased)
+		}
+	}
+	for i := 0; i < numTasks; i++ {
+		if n := len(completed[strconv.Itoa(i)]); n != 1 {
+			t.Errorf("task %v completed %v times, want 1", i, n)
+		}
+	}
+	for task := range held {
+		got := completed[task]
+		if len(got) == 1 && (strings.HasPrefix(got[0].Worker, "w0-") || got[0].Task.Attempts < 2) {
+			t.Errorf("task %v held by the killed worker completed on %v after %v attempts", task, got[0].Worker, got[0].Task.Attempts)
+		}
+	}
+
+	// 'Wait' returns once the 2 live workers heard they are done, they exit cleanly
+	c.Wait()
+	for i, cmd := range workers {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("worker %v exited with %v", i+1, err)
+		}
+	}
+}
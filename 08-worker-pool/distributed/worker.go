@@ -0,0 +1,182 @@
+package distributed
+
+import (
+	"context"
+	"log"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// 'Handler' runs one task in a worker process
+type Handler func(ctx context.Context, payload []byte) ([]byte, error)
+
+// 'WorkerConfig' configures a worker process
+// 'Concurrency' is the number of local workers ('pool.Pool' size), the worker leases at most that many tasks
+type WorkerConfig struct {
+	Name        string
+	Concurrency int
+	Logger      *log.Logger
+}
+
+// 'RunWorker' connects to the coordinator at 'addr' and runs leased tasks on a local 'pool.Pool'
+// it returns once the coordinator reports every task finished or 'ctx' is cancelled
+// a heartbeat goroutine extends the leases of the tasks in flight, a task whose lease was lost is cancelled
+func RunWorker(ctx context.Context, addr string, config WorkerConfig, handler Handler) error {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+
+	client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var registered RegisterReply
+	if err := client.Call("Coordinator.Register", RegisterArgs{Name: config.Name}, &registered); err != nil {
+		return err
+	}
+	workerID := registered.WorkerID
+	config.Logger.Printf("worker %v: registered with %v", workerID, addr)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 'inFlight' is every leased task not completed yet, with the cancel func of its context
+	// 'lost' are the tasks whose lease expired, they are not reported (another worker owns them now)
+	var mu sync.Mutex
+	inFlight := make(map[uint64]context.CancelFunc)
+	lost := make(map[uint64]bool)
+
+	run := func(_ context.Context, task Task) ([]byte, error) {
+		taskCtx, taskCancel := context.WithCancel(ctx)
+		defer taskCancel()
+		mu.Lock()
+		inFlight[task.ID] = taskCancel
+		mu.Unlock()
+		return handler(taskCtx, task.Payload)
+	}
+	workers := pool.NewContext(ctx, config.Concurrency, run)
+
+	// 'slots' is the number of tasks this worker may still lease
+	slots := make(chan struct{}, config.Concurrency)
+	for i := 0; i < config.Concurrency; i++ {
+		slots <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+
+	// report every result and give its slot back
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for result := range workers.Results() {
+			mu.Lock()
+			delete(inFlight, result.Task.ID)
+			wasLost := lost[result.Task.ID]
+			delete(lost, result.Task.ID)
+			mu.Unlock()
+
+			// a task cancelled because this worker stops (or lost its lease) is left to lease expiry
+			// so the coordinator dispatches it again
+			if wasLost || result.Status == pool.Cancelled || result.Status == pool.NotStarted {
+				slots <- struct{}{}
+				continue
+			}
+
+			args := CompleteArgs{WorkerID: workerID, TaskID: result.Task.ID, Result: result.Value}
+			if result.Err != nil {
+				args.Err = result.Err.Error()
+			}
+			var reply CompleteReply
+			if err := client.Call("Coordinator.Complete", args, &reply); err != nil {
+				config.Logger.Printf("worker %v: complete task %v: %v", workerID, result.Task.ID, err)
+			}
+			slots <- struct{}{}
+		}
+	}()
+
+	// heartbeat the leases in flight, cancel the tasks the coordinator says are lost
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(registered.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			mu.Lock()
+			ids := make([]uint64, 0, len(inFlight))
+			for id := range inFlight {
+				ids = append(ids, id)
+			}
+			mu.Unlock()
+
+			var reply HeartbeatReply
+			if err := client.Call("Coordinator.Heartbeat", HeartbeatArgs{WorkerID: workerID, TaskIDs: ids}, &reply); err != nil {
+				config.Logger.Printf("worker %v: heartbeat: %v", workerID, err)
+				continue
+			}
+			mu.Lock()
+			for _, id := range reply.Lost {
+				if taskCancel, ok := inFlight[id]; ok {
+					config.Logger.Printf("worker %v: lease of task %v lost, cancelling it", workerID, id)
+					lost[id] = true
+					taskCancel()
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+
+	// lease loop: wait for a free slot, lease as many tasks as there are free slots
+	err = func() error {
+		defer workers.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-slots:
+			}
+			free := 1
+			for len(slots) > 0 {
+				<-slots
+				free++
+			}
+
+			var reply LeaseReply
+			if err := client.Call("Coordinator.Lease", LeaseArgs{WorkerID: workerID, Max: free, Wait: time.Second}, &reply); err != nil {
+				return err
+			}
+			if reply.Done {
+				return nil
+			}
+
+			for _, task := range reply.Tasks {
+				if err := workers.Submit(task); err != nil {
+					return err
+				}
+			}
+			for i := len(reply.Tasks); i < free; i++ {
+				slots <- struct{}{}
+			}
+		}
+	}()
+
+	workers.Wait()
+	cancel()
+	wg.Wait()
+
+	config.Logger.Printf("worker %v: finished", workerID)
+	return err
+}
@@ -0,0 +1,156 @@
+package httpload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+// 'Response' is the result of one HTTP task
+type Response struct {
+	Status  int
+	Latency time.Duration
+	Reused  bool
+}
+
+// 'StatusError' is a non-2xx response
+// 5xx and '429 Too Many Requests' responses are retryable, other 4xx responses are 'pool.Permanent'
+type StatusError struct {
+	Status int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("http status %v %v", e.Status, http.StatusText(e.Status))
+}
+
+// 'Client' sends the HTTP tasks and counts connection reuse
+type Client struct {
+	http    *http.Client
+	baseURL string
+
+	connections atomic.Int64
+	reused      atomic.Int64
+}
+
+// 'NewClient' keeps up to 'maxIdle' idle connections to 'baseURL' so every worker can reuse its connection
+func NewClient(baseURL string, maxIdle int) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdle
+	transport.MaxIdleConnsPerHost = maxIdle
+	return &Client{
+		http:    &http.Client{Transport: transport},
+		baseURL: baseURL,
+	}
+}
+
+// 'Get' is the pool task: GET '{baseURL}/api/{id}'
+// the body is read to the end and closed so the connection goes back to the idle pool
+func (c *Client) Get(ctx context.Context, id int) (Response, error) {
+	var response Response
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			response.Reused = info.Reused
+			if info.Reused {
+				c.reused.Add(1)
+			} else {
+				c.connections.Add(1)
+			}
+		},
+	}
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/api/%v", c.baseURL, id), nil)
+	if err != nil {
+		return response, pool.Permanent(err)
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(request)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	response.Status = resp.StatusCode
+	response.Latency = time.Since(start)
+	if err != nil {
+		return response, err
+	}
+
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return response, StatusError{Status: resp.StatusCode}
+	case resp.StatusCode >= 400:
+		return response, pool.Permanent(StatusError{Status: resp.StatusCode})
+	}
+	return response, nil
+}
+
+// 'Connections' is how many new connections were dialed and how many requests reused an idle one
+func (c *Client) Connections() (dialed, reused int64) {
+	return c.connections.Load(), c.reused.Load()
+}
+
+// 'CloseIdleConnections' closes the kept-alive connections
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+// 'StatusReport' counts responses and keeps a latency histogram per HTTP status code
+// status 0 collects tasks that got no response at all (connection errors, cancelled requests)
+type StatusReport struct {
+	mu         sync.Mutex
+	histograms map[int]*pool.Histogram
+}
+
+func NewStatusReport() *StatusReport {
+	return &StatusReport{histograms: make(map[int]*pool.Histogram)}
+}
+
+// 'Observe' adds one response
+func (s *StatusReport) Observe(response Response) {
+	s.mu.Lock()
+	histogram, ok := s.histograms[response.Status]
+	if !ok {
+		histogram = pool.NewHistogram(nil)
+		s.histograms[response.Status] = histogram
+	}
+	s.mu.Unlock()
+
+	histogram.Observe(response.Latency)
+}
+
+// 'Statuses' returns the observed status codes in ascending order
+func (s *StatusReport) Statuses() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]int, 0, len(s.histograms))
+	for status := range s.histograms {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	return statuses
+}
+
+// 'Histogram' returns the latency histogram of one status code
+func (s *StatusReport) Histogram(status int) pool.HistogramSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	histogram, ok := s.histograms[status]
+	if !ok {
+		return pool.NewHistogram(nil).Snapshot()
+	}
+	return histogram.Snapshot()
+}
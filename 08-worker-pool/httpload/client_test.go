@@ -0,0 +1,53 @@
+package httpload
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/alexsmith716/go-concurrency/08-worker-pool/pool"
+)
+
+func TestGetStatus(t *testing.T) {
+	// 5xx and 429 are retried, the other 4xx are permanent
+	tests := []struct {
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNoContent, false, false},
+		{http.StatusBadRequest, true, true},
+		{http.StatusNotFound, true, true},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusInternalServerError, true, false},
+		{http.StatusServiceUnavailable, true, false},
+	}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			server := NewServer(ServerConfig{Statuses: map[int]int{test.status: 1}})
+			defer server.Close()
+			client := NewClient(server.URL, 1)
+			defer client.CloseIdleConnections()
+
+			response, err := client.Get(context.Background(), 1)
+			if response.Status != test.status {
+				t.Errorf("got status %v, want %v", response.Status, test.status)
+			}
+			if !test.wantErr {
+				if err != nil {
+					t.Errorf("got %v, want no error", err)
+				}
+				return
+			}
+			var statusErr StatusError
+			if !errors.As(err, &statusErr) || statusErr.Status != test.status {
+				t.Errorf("got %v, want a 'StatusError' of %v", err, test.status)
+			}
+			if pool.IsPermanent(err) != test.wantPermanent {
+				t.Errorf("%v is permanent: %v, want %v", err, pool.IsPermanent(err), test.wantPermanent)
+			}
+		})
+	}
+}
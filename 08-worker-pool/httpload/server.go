@@ -0,0 +1,144 @@
+package httpload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// package 'httpload' turns the simulated 'apiRequest()' into real HTTP requests against a bundled local server
+// the server latency distribution, error rate and status codes are configurable so the worker pool
+// exercises real I/O, connection reuse and error handling
+
+// 'Distribution' is the shape of the server latency
+type Distribution string
+
+const (
+	// 'Fixed' every response waits exactly 'Latency'
+	Fixed Distribution = "fixed"
+	// 'Uniform' waits between 0 and 2*'Latency' (mean 'Latency')
+	Uniform Distribution = "uniform"
+	// 'Exponential' waits an exponentially distributed time with mean 'Latency' (long tail)
+	Exponential Distribution = "exponential"
+	// 'Normal' waits a normally distributed time with mean 'Latency' and standard deviation 'Latency'/4
+	Normal Distribution = "normal"
+)
+
+// 'ServerConfig' configures the local test server
+// 'ErrorRate' is the chance (0-1) of answering '500 Internal Server Error'
+// 'Statuses' weights the status codes of the other responses, e.g. {200: 95, 404: 5} (empty = always 200)
+type ServerConfig struct {
+	Latency      time.Duration
+	Distribution Distribution
+	ErrorRate    float64
+	Statuses     map[int]int
+}
+
+// 'NewServer' starts an 'httptest.Server' answering every path with the configured latency and status codes
+// the caller must 'Close()' the server
+func NewServer(config ServerConfig) *httptest.Server {
+	return httptest.NewServer(NewHandler(config))
+}
+
+// 'NewHandler' is the server handler, usable with a standalone 'http.ListenAndServe'
+func NewHandler(config ServerConfig) http.Handler {
+	h := &handler{config: config, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for status, weight := range config.Statuses {
+		if weight > 0 {
+			h.statuses = append(h.statuses, status)
+			h.totalWeight += weight
+		}
+	}
+	sort.Ints(h.statuses)
+	return h
+}
+
+type handler struct {
+	config      ServerConfig
+	statuses    []int
+	totalWeight int
+
+	// 'rand.Rand' is not safe for concurrent use
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	latency, status := h.next()
+
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"path":%q,"status":%v,"latency_ms":%v}`, r.URL.Path, status, latency.Milliseconds())
+}
+
+// 'next' draws the latency and status code of one response
+func (h *handler) next() (time.Duration, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	mean := float64(h.config.Latency)
+	var latency float64
+	switch h.config.Distribution {
+	case Uniform:
+		latency = h.rand.Float64() * 2 * mean
+	case Exponential:
+		latency = h.rand.ExpFloat64() * mean
+	case Normal:
+		latency = math.Max(0, h.rand.NormFloat64()*mean/4+mean)
+	default:
+		latency = mean
+	}
+
+	if h.rand.Float64() < h.config.ErrorRate {
+		return time.Duration(latency), http.StatusInternalServerError
+	}
+	if h.totalWeight == 0 {
+		return time.Duration(latency), http.StatusOK
+	}
+
+	pick := h.rand.Intn(h.totalWeight)
+	for _, status := range h.statuses {
+		pick -= h.config.Statuses[status]
+		if pick < 0 {
+			return time.Duration(latency), status
+		}
+	}
+	return time.Duration(latency), http.StatusOK
+}
+
+// 'ParseStatuses' reads "200:95,404:5" into status code weights
+func ParseStatuses(list string) (map[int]int, error) {
+	statuses := make(map[int]int)
+	if strings.TrimSpace(list) == "" {
+		return statuses, nil
+	}
+	for _, field := range strings.Split(list, ",") {
+		code, weight, found := strings.Cut(strings.TrimSpace(field), ":")
+		if !found {
+			weight = "1"
+		}
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid status code %q", code)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q for status %v", weight, status)
+		}
+		statuses[status] = w
+	}
+	return statuses, nil
+}
@@ -0,0 +1,31 @@
+package httpload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStatuses(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    map[int]int
+		wantErr bool
+	}{
+		{"", map[int]int{}, false},
+		{"  ", map[int]int{}, false},
+		{"200:95,404:5", map[int]int{200: 95, 404: 5}, false},
+		{" 200:95 , 503 ", map[int]int{200: 95, 503: 1}, false},
+		{"200:0", map[int]int{200: 0}, false},
+		{"abc:1", nil, true},
+		{"99:1", nil, true},
+		{"600:1", nil, true},
+		{"200:x", nil, true},
+		{"200:-1", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseStatuses(test.list)
+		if (err != nil) != test.wantErr || !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseStatuses(%q) = %v, %v, want %v", test.list, got, err, test.want)
+		}
+	}
+}
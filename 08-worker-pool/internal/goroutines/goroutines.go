@@ -0,0 +1,54 @@
+// package 'goroutines' samples the goroutine count for the examples and benchmarks, it is not part of the pool API
+package goroutines
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// 'Sampler' records the peak 'runtime.NumGoroutine()' seen while it runs
+type Sampler struct {
+	peak atomic.Int64
+	stop chan struct{}
+	done chan struct{}
+}
+
+// 'Sample' starts sampling the goroutine count every 'interval'
+func Sample(interval time.Duration) *Sampler {
+	g := &Sampler{stop: make(chan struct{}), done: make(chan struct{})}
+	g.sample()
+
+	go func() {
+		defer close(g.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				g.sample()
+			}
+		}
+	}()
+	return g
+}
+
+func (g *Sampler) sample() {
+	n := int64(runtime.NumGoroutine())
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+// 'Stop' ends sampling and returns the peak goroutine count
+func (g *Sampler) Stop() int {
+	close(g.stop)
+	<-g.done
+	g.sample()
+	return int(g.peak.Load())
+}
@@ -0,0 +1,111 @@
+// package 'semaphore' bounds the '02-example-asynch' "goroutine per task" style, it is not part of the pool API
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// 'ErrTooLarge' is returned by 'Acquire' for more units than the size, they could never be held
+var ErrTooLarge = errors.New("semaphore: acquiring more units than the size")
+
+// 'Semaphore' is a weighted semaphore: at most 'size' units are held at once (acquire before 'go fetch()', release when done)
+// waiters are served first in, first out so a large 'Acquire' is not starved by small ones
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// 'New' allows at most 'size' units in flight
+func New(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// 'Acquire' blocks until 'n' units are available or 'ctx' is cancelled
+// more than 'size' units fail with 'ErrTooLarge' instead of waiting forever in front of every other waiter
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := semaphoreWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// acquired right as the context was cancelled, give the units back
+			s.cur -= n
+			s.notify()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// the front waiter leaving may unblock the ones behind it
+			if isFront && s.size > s.cur {
+				s.notify()
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// 'TryAcquire' takes 'n' units without blocking and reports whether it did
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// 'Release' gives back 'n' units and wakes the waiters that now fit
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notify()
+}
+
+// 'notify' hands units to waiters in order until the front one does not fit
+func (s *Semaphore) notify() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
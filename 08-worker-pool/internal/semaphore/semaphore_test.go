@@ -0,0 +1,141 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 'acquire' calls 'Acquire' in a goroutine and waits until it is queued (or returned), the error is sent on the channel
+func acquire(t *testing.T, s *Semaphore, ctx context.Context, n int64) <-chan error {
+	t.Helper()
+	s.mu.Lock()
+	queued := s.waiters.Len()
+	s.mu.Unlock()
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- s.Acquire(ctx, n)
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		waiting := s.waiters.Len()
+		s.mu.Unlock()
+		if waiting > queued || len(acquired) > 0 {
+			return acquired
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Acquire neither returned nor queued")
+		}
+	}
+}
+
+// 'pending' reports whether 'Acquire' is still blocked
+func pending(acquired <-chan error) bool {
+	select {
+	case <-acquired:
+		return false
+	case <-time.After(10 * time.Millisecond):
+		return true
+	}
+}
+
+// a small 'Acquire' queued behind a large one waits for it, even when it would fit
+func TestFIFO(t *testing.T) {
+	s := New(2)
+	if !s.TryAcquire(2) {
+		t.Fatal("TryAcquire failed on an unused semaphore")
+	}
+	large := acquire(t, s, context.Background(), 2)
+	small := acquire(t, s, context.Background(), 1)
+
+	s.Release(1)
+	if !pending(large) || !pending(small) {
+		t.Fatal("an Acquire returned with 1 unit free and the large one first in line")
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire jumped the queue")
+	}
+
+	s.Release(1)
+	if err := <-large; err != nil {
+		t.Fatal(err)
+	}
+	if !pending(small) {
+		t.Fatal("the small Acquire returned while the large one holds every unit")
+	}
+	s.Release(2)
+	if err := <-small; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a cancelled waiter leaves the queue, when it was first in line the waiters behind it that now fit are served
+func TestCancelQueued(t *testing.T) {
+	s := New(2)
+	s.TryAcquire(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	large := acquire(t, s, ctx, 2)
+	small := acquire(t, s, context.Background(), 1)
+
+	s.Release(1)
+	cancel()
+	if err := <-large; !errors.Is(err, context.Canceled) {
+		t.Errorf("the cancelled Acquire returned %v", err)
+	}
+	if err := <-small; err != nil {
+		t.Fatal(err)
+	}
+	if s.cur != 2 || s.waiters.Len() != 0 {
+		t.Errorf("%v units held and %v waiters, want 2 and 0", s.cur, s.waiters.Len())
+	}
+}
+
+// a waiter granted its units right as its context is cancelled either keeps them or gives them back, none leak
+func TestCancelGranted(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		s := New(1)
+		s.TryAcquire(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		acquired := acquire(t, s, ctx, 1)
+
+		cancel()
+		s.Release(1)
+		err := <-acquired
+
+		s.mu.Lock()
+		held := s.cur
+		s.mu.Unlock()
+		switch {
+		case err == nil && held != 1:
+			t.Fatalf("the Acquire succeeded and %v units are held, want 1", held)
+		case err != nil && held != 0:
+			t.Fatalf("the Acquire returned %v and %v units are held, want 0", err, held)
+		}
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	s := New(2)
+	if err := s.Acquire(context.Background(), 3); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Acquire of 3 units of 2 returned %v, want ErrTooLarge", err)
+	}
+	if s.TryAcquire(3) {
+		t.Error("TryAcquire of 3 units of 2 succeeded")
+	}
+	if !s.TryAcquire(2) {
+		t.Error("the refused Acquire blocks the semaphore")
+	}
+}
+
+func TestOverRelease(t *testing.T) {
+	s := New(2)
+	s.TryAcquire(1)
+	defer func() {
+		if recover() == nil {
+			t.Error("releasing more than held did not panic")
+		}
+	}()
+	s.Release(2)
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"time"
+)
+
+// 'AutoscalePolicy' grows and shrinks the pool between 'MinWorkers' and 'MaxWorkers'
+// every 'Interval' the autoscaler looks at the queue depth ('len(bufferedChannel)') and at idle workers:
+// -tasks waiting in the queue grow the pool by 'Step' workers
+// -workers idle for longer than 'IdleTimeout' (with an empty queue) are removed, 'Step' at a time
+type AutoscalePolicy struct {
+	MinWorkers  int
+	MaxWorkers  int
+	Step        int
+	Interval    time.Duration
+	IdleTimeout time.Duration
+}
+
+func (a AutoscalePolicy) withDefaults() AutoscalePolicy {
+	if a.MinWorkers < 1 {
+		a.MinWorkers = 1
+	}
+	if a.MaxWorkers < a.MinWorkers {
+		a.MaxWorkers = a.MinWorkers
+	}
+	if a.Step < 1 {
+		a.Step = 1
+	}
+	if a.Interval <= 0 {
+		a.Interval = 100 * time.Millisecond
+	}
+	if a.IdleTimeout <= 0 {
+		a.IdleTimeout = time.Second
+	}
+	return a
+}
+
+// 'autoscale' runs until every worker has returned
+func (p *Pool[T, R]) autoscale(policy AutoscalePolicy) {
+	policy = policy.withDefaults()
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	// when the pool last had no idle worker or a non-empty queue
+	lastBusy := time.Now()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			size := p.targetSize()
+			depth := p.QueueDepth()
+			idle := size - p.Busy()
+
+			switch {
+			case depth > 0:
+				lastBusy = now
+				if size < policy.MaxWorkers {
+					p.Resize(min(size+policy.Step, policy.MaxWorkers))
+				}
+			case idle <= 0:
+				lastBusy = now
+			case now.Sub(lastBusy) >= policy.IdleTimeout && size > policy.MinWorkers:
+				p.Resize(max(size-min(policy.Step, idle), policy.MinWorkers))
+				lastBusy = now
+			}
+		}
+	}
+}
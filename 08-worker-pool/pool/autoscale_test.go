@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 'eventually' polls 'condition' until it holds, it fails the test after 5 seconds
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v never happened", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestResize(t *testing.T) {
+	for _, scheduler := range []Scheduler{ChannelScheduler, WorkStealing} {
+		t.Run(scheduler.String(), func(t *testing.T) {
+			var running atomic.Int64
+			gate := make(chan struct{})
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				running.Add(1)
+				defer running.Add(-1)
+				<-gate
+				return data, nil
+			}, WithScheduler(scheduler))
+			drained := make(chan int)
+			go func() { drained <- drain(p) }()
+
+			// 8 workers run 8 tasks at once
+			p.Resize(8)
+			eventually(t, "8 workers", func() bool { return p.Size() == 8 })
+			for i := 0; i < 8; i++ {
+				if err := p.Submit(i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			eventually(t, "8 tasks running at once", func() bool { return running.Load() == 8 })
+			close(gate)
+
+			// the removed workers exit once they are idle
+			p.Resize(2)
+			eventually(t, "the shrink to 2 workers", func() bool { return p.Size() == 2 })
+			p.Resize(0)
+			if p.targetSize() != 1 {
+				t.Errorf("the target is %v after 'Resize(0)', want 1", p.targetSize())
+			}
+
+			p.Close()
+			if n := <-drained; n != 8 {
+				t.Errorf("got %v results, want 8", n)
+			}
+			p.Wait()
+
+			// a closed pool is not resized
+			p.Resize(4)
+			if size := p.targetSize(); size != 1 {
+				t.Errorf("a closed pool was resized to %v", size)
+			}
+		})
+	}
+}
+
+// a queue grows the pool by 'Step' up to 'MaxWorkers', idle workers shrink it back to 'MinWorkers'
+func TestAutoscale(t *testing.T) {
+	var most atomic.Int64
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return data, nil
+	}, WithAutoscale(AutoscalePolicy{MinWorkers: 1, MaxWorkers: 6, Step: 2, Interval: 5 * time.Millisecond, IdleTimeout: 50 * time.Millisecond}))
+	drained := make(chan int)
+	go func() { drained <- drain(p) }()
+
+	// the autoscaler never goes past 'MaxWorkers'
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				if size := int64(p.Size()); size > most.Load() {
+					most.Store(size)
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		if err := p.Submit(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eventually(t, "the growth to 6 workers", func() bool { return p.targetSize() == 6 })
+	eventually(t, "the shrink to 1 worker", func() bool { return p.Size() == 1 })
+	if most.Load() > 6 {
+		t.Errorf("the pool grew to %v workers, at most 6", most.Load())
+	}
+
+	p.Close()
+	if n := <-drained; n != 200 {
+		t.Errorf("got %v results, want 200", n)
+	}
+	p.Wait()
+}
@@ -0,0 +1,255 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// many APIs accept a batch of items per call, one call for 10 items costs about one round trip instead of 10
+// a batch pool is a 'Pool' whose workers collect up to 'MaxSize' tasks (waiting at most 'MaxWait' after the first)
+// and hand them to a 'BatchTask' in one call, the values it returns are split back into one 'Result' per task
+// -the tasks are submitted, prioritized, closed, shut down and reported on exactly as in a 'Pool'
+// -'WithRetry', 'WithRateLimiter', 'WithCircuitBreaker' and 'WithAdaptiveLimit' count a batch as one call
+// -a 'BatchError' fails single tasks of a batch, those are not retried, any other error fails (or retries) the whole batch
+// -a panicking batch is not run again, every task of it is 'Quarantined'
+// batching always uses the 'ChannelScheduler', 'WithHedging' and 'SubmitKey' are not supported
+
+// 'ErrBatchResults' is the error of every task of a batch whose 'BatchTask' returned the wrong number of values
+var ErrBatchResults = errors.New("pool: batch task returned the wrong number of results")
+
+// 'ErrKeyedBatch' is returned by 'SubmitKey' on a batch pool
+var ErrKeyedBatch = errors.New("pool: keyed tasks are not supported by a batch pool")
+
+// 'BatchTask' is the function a worker calls per batch, it returns one value per task in the same order
+type BatchTask[T, R any] func(ctx context.Context, batch []T) ([]R, error)
+
+// 'BatchError' fails single tasks of a batch: 'Errs[i]' is the error of task i (nil = succeeded)
+type BatchError struct {
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	for _, err := range e.Errs {
+		if err != nil {
+			failed++
+		}
+	}
+	return fmt.Sprintf("pool: %v of %v batch tasks failed", failed, len(e.Errs))
+}
+
+// 'BatchPolicy' is when a worker stops collecting and calls the 'BatchTask'
+// 'MaxSize' tasks are collected (10) or 'MaxWait' has passed since the first one (10ms), whichever comes first
+type BatchPolicy struct {
+	MaxSize int
+	MaxWait time.Duration
+}
+
+// 'BatchStats' is how many batches were called and how full they were
+type BatchStats struct {
+	Batches int
+	Tasks   int
+	Full    int
+}
+
+// 'batcher' is the batch task and policy of a batch pool
+type batcher[T, R any] struct {
+	task   BatchTask[T, R]
+	policy BatchPolicy
+}
+
+// 'NewBatch' starts 'numberOfWorkers' goroutines which each call 'task' with batches of the queued tasks
+func NewBatch[T, R any](numberOfWorkers int, task BatchTask[T, R], policy BatchPolicy, options ...Option) *Pool[T, R] {
+	return NewBatchContext(context.Background(), numberOfWorkers, task, policy, options...)
+}
+
+// 'NewBatchContext' is 'NewBatch' bound to a parent context (see 'NewContext')
+func NewBatchContext[T, R any](ctx context.Context, numberOfWorkers int, task BatchTask[T, R], policy BatchPolicy, options ...Option) *Pool[T, R] {
+	if policy.MaxSize < 1 {
+		policy.MaxSize = 10
+	}
+	if policy.MaxWait <= 0 {
+		policy.MaxWait = 10 * time.Millisecond
+	}
+
+	p := newPool[T, R](ctx, numberOfWorkers, nil, options)
+	p.config.scheduler = ChannelScheduler
+	p.hedge = nil
+	p.batch = &batcher[T, R]{task: task, policy: policy}
+	p.start()
+	return p
+}
+
+// 'collect' adds queued tasks to 'first' until the batch is full, 'MaxWait' has passed or the pool is drained
+func (p *Pool[T, R]) collect(first job[T]) []job[T] {
+	policy := p.batch.policy
+	batch := make([]job[T], 1, policy.MaxSize)
+	batch[0] = first
+
+	timer := time.NewTimer(policy.MaxWait)
+	defer timer.Stop()
+	for len(batch) < policy.MaxSize {
+		select {
+		case j, open := <-p.bufferedChannel:
+			if !open {
+				return batch
+			}
+			batch = append(batch, j)
+		case j := <-p.requeued:
+			batch = append(batch, j)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// 'processBatch' runs one batch and sends a result per task
+func (p *Pool[T, R]) processBatch(batch []job[T]) {
+	p.busy.Add(1)
+	p.mu.Lock()
+	p.report.Batches.Batches++
+	p.report.Batches.Tasks += len(batch)
+	if len(batch) == p.batch.policy.MaxSize {
+		p.report.Batches.Full++
+	}
+	p.mu.Unlock()
+
+	started := time.Now()
+	var results []Result[T, R]
+	if p.ctx.Err() != nil {
+		results = make([]Result[T, R], len(batch))
+		for i, j := range batch {
+			results[i] = Result[T, R]{Task: j.data, Err: ErrStopped, Status: NotStarted}
+		}
+	} else {
+		results = p.runBatch(batch)
+	}
+
+	// the worker counted as 1 busy worker, 'finish' counts every task off
+	p.busy.Add(int64(len(batch) - 1))
+	for i, j := range batch {
+		if p.requeue(results[i]) {
+			p.busy.Add(-1)
+			p.park(j)
+			continue
+		}
+		p.finish(j, results[i], started)
+	}
+}
+
+// 'runBatch' calls the batch task (retrying the whole batch with a 'RetryPolicy') and splits its values per task
+func (p *Pool[T, R]) runBatch(batch []job[T]) []Result[T, R] {
+	data := make([]T, len(batch))
+	for i, j := range batch {
+		data[i] = j.data
+	}
+
+	retry := p.config.retry
+	if retry.Budget != nil {
+		retry.Budget.deposit()
+	}
+
+	var values []R
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		values, err = p.attemptBatch(data)
+		if err == nil || p.ctx.Err() != nil {
+			break
+		}
+		var batchErr *BatchError
+		var panicErr *PanicError
+		if errors.As(err, &batchErr) || errors.As(err, &panicErr) || errors.Is(err, ErrCircuitOpen) {
+			break
+		}
+		if attempts >= retry.MaxAttempts || !retry.retryable(err) {
+			break
+		}
+		if retry.Budget != nil && !retry.Budget.withdraw() {
+			break
+		}
+		if sleep(p.ctx, retry.backoff(attempts)) != nil {
+			break
+		}
+	}
+
+	var batchErr *BatchError
+	var panicErr *PanicError
+	errors.As(err, &batchErr)
+	errors.As(err, &panicErr)
+
+	results := make([]Result[T, R], len(batch))
+	for i := range batch {
+		result := Result[T, R]{Task: data[i], Attempts: attempts}
+		switch {
+		case err == nil && len(values) != len(batch):
+			result.Err = ErrBatchResults
+		case err == nil:
+			result.Value = values[i]
+		case batchErr != nil && len(batchErr.Errs) == len(batch):
+			result.Err = batchErr.Errs[i]
+			if result.Err == nil && len(values) == len(batch) {
+				result.Value = values[i]
+			}
+		default:
+			result.Err = err
+		}
+
+		switch {
+		case result.Err == nil:
+			result.Status = Succeeded
+		case panicErr != nil:
+			result.Status = Quarantined
+		case p.ctx.Err() != nil:
+			result.Status = Cancelled
+		default:
+			result.Status = Failed
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// 'attemptBatch' is one call of the batch task
+// a 'BatchError' means the call itself went through, so it is not a failure for the breaker and the adaptive limiter
+func (p *Pool[T, R]) attemptBatch(data []T) ([]R, error) {
+	var values []R
+	var callErr error
+	err := p.guard(p.ctx, func() error {
+		values, callErr = p.callBatch(data)
+		var batchErr *BatchError
+		if errors.As(callErr, &batchErr) {
+			return nil
+		}
+		return callErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, callErr
+}
+
+// 'callBatch' calls the batch task once with the per-task deadline
+// a panic is recovered and returned as a '*PanicError', the batch is not run again
+func (p *Pool[T, R]) callBatch(data []T) (values []R, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			stack := debug.Stack()
+			p.recordPanic(PanicRecord[T]{Task: data[0], Batch: append([]T(nil), data...), Value: value, Stack: string(stack)}, stack)
+			values, err = nil, &PanicError{Value: value, Stack: stack, Panics: 1}
+		}
+	}()
+
+	ctx := p.ctx
+	if p.config.taskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.taskTimeout)
+		defer cancel()
+	}
+	return p.batch.task(ctx, data)
+}
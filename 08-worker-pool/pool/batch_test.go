@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// a panicking batch quarantines every task of it and is recorded once with all its tasks
+func TestBatchPanic(t *testing.T) {
+	var reported int
+	p := NewBatch(1, func(ctx context.Context, batch []int) ([]int, error) {
+		if slices.Contains(batch, 2) {
+			var response map[string]int
+			response["id"] = batch[0] // assignment to entry in nil map
+		}
+		return batch, nil
+	}, BatchPolicy{MaxSize: 3, MaxWait: time.Hour}, WithPanicPolicy(PanicPolicy{OnPanic: func(value any, stack []byte) {
+		reported++
+	}}))
+
+	go func() {
+		defer p.Close()
+		for i := 0; i < 6; i++ {
+			p.Submit(i)
+		}
+	}()
+	for result := range p.Results() {
+		var panicErr *PanicError
+		switch {
+		case result.Task < 3 && (result.Status != Quarantined || !errors.As(result.Err, &panicErr)):
+			t.Errorf("task %v of the panicking batch ended %v: %v", result.Task, result.Status, result.Err)
+		case result.Task >= 3 && result.Status != Succeeded:
+			t.Errorf("task %v ended %v: %v", result.Task, result.Status, result.Err)
+		}
+	}
+	p.Wait()
+
+	report := p.Report()
+	if len(report.Panics) != 1 || reported != 1 {
+		t.Fatalf("%v panics recorded and %v reported, want 1", len(report.Panics), reported)
+	}
+	if record := report.Panics[0]; record.Task != 0 || !slices.Equal(record.Batch, []int{0, 1, 2}) || record.Stack == "" {
+		t.Errorf("got the record %+v", record)
+	}
+}
@@ -0,0 +1,410 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// when the downstream behind the task is failing every worker keeps calling it, each call waiting for its timeout
+// a 'CircuitBreaker' stops the calls for a while once too many of the recent ones failed:
+// -'BreakerClosed' every call goes through, the outcome of the last 'Window' calls is kept
+// -'BreakerOpen' the failure rate reached 'FailureRate', no call goes through until 'CoolDown' has passed
+// -'BreakerHalfOpen' after the cool-down 'TrialCalls' calls go through, if all of them succeed the breaker closes,
+// a failed trial opens it again for another cool-down
+// a task refused by an open breaker fails with 'ErrCircuitOpen' or is re-queued, see 'BreakerAction'
+// a re-queued task is parked until the breaker changes state, the state change wakes every parked task at once
+
+// 'ErrCircuitOpen' is the error of a task refused by an open (or half-open and busy) circuit breaker
+var ErrCircuitOpen = errors.New("pool: circuit breaker open")
+
+// 'BreakerState' is the state of a 'CircuitBreaker'
+type BreakerState int
+
+const (
+	// 'BreakerClosed' calls go through and their outcome is recorded (default)
+	BreakerClosed BreakerState = iota
+	// 'BreakerOpen' calls are refused until the cool-down has passed
+	BreakerOpen
+	// 'BreakerHalfOpen' a few trial calls go through to test the downstream
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// 'BreakerAction' is what the pool does with a task the breaker refused
+type BreakerAction int
+
+const (
+	// 'BreakerFail' the task fails right away with 'ErrCircuitOpen' (default)
+	BreakerFail BreakerAction = iota
+	// 'BreakerRequeue' the task is put back and run on the next state change (the end of the cool-down), it does not fail
+	BreakerRequeue
+)
+
+func (a BreakerAction) String() string {
+	switch a {
+	case BreakerFail:
+		return "fail"
+	case BreakerRequeue:
+		return "requeue"
+	}
+	return "unknown"
+}
+
+// 'BreakerPolicy' configures a 'CircuitBreaker', zero fields get the defaults in brackets
+// -'Window' is the number of recent calls the failure rate is computed over (20)
+// -'MinCalls' is the number of calls in the window before the breaker may open (10)
+// -'FailureRate' opens the breaker once that share of the window failed (0.5)
+// -'CoolDown' is how long the breaker stays open before the trial calls (1s)
+// -'TrialCalls' is the number of calls let through while half-open, all must succeed to close (3)
+// -'IsFailure' classifies errors (nil counts every error), 'context.Canceled' (the pool stopping) is never counted
+// and a call which panicked always is
+// -'OnStateChange' is called on every state change, 'Logger' logs it (nil = not logged)
+type BreakerPolicy struct {
+	Window        int
+	MinCalls      int
+	FailureRate   float64
+	CoolDown      time.Duration
+	TrialCalls    int
+	OnOpen        BreakerAction
+	IsFailure     func(err error) bool
+	OnStateChange func(change BreakerChange)
+	Logger        *log.Logger
+}
+
+// 'BreakerChange' is one state change, 'FailureRate' is the rate of the window when it happened
+// (the window is only kept while closed, it is 0 for a change out of 'BreakerOpen' or 'BreakerHalfOpen')
+type BreakerChange struct {
+	Time        time.Time
+	From        BreakerState
+	To          BreakerState
+	FailureRate float64
+}
+
+// 'BreakerStats' is the current state, the calls the breaker let through or refused and every state change
+type BreakerStats struct {
+	State    BreakerState
+	Allowed  int
+	Rejected int
+	Changes  []BreakerChange
+}
+
+// 'CircuitBreaker' is shared by every worker of a pool (or several pools calling the same downstream)
+type CircuitBreaker struct {
+	policy BreakerPolicy
+
+	mu       sync.Mutex
+	state    BreakerState
+	outcomes []bool
+	next     int
+	calls    int
+	failures int
+	openedAt time.Time
+	trials   int
+	passed   int
+	// 'generation' changes with the state, so a call let through in an earlier state is not counted as a trial
+	generation uint64
+	// 'changed' is closed (and replaced) on every state change and when a trial call is handed back
+	changed chan struct{}
+
+	allowed  int
+	rejected int
+	changes  []BreakerChange
+}
+
+// 'NewCircuitBreaker' returns a closed breaker
+func NewCircuitBreaker(policy BreakerPolicy) *CircuitBreaker {
+	if policy.Window < 1 {
+		policy.Window = 20
+	}
+	if policy.MinCalls < 1 {
+		policy.MinCalls = min(10, policy.Window)
+	}
+	if policy.FailureRate <= 0 {
+		policy.FailureRate = 0.5
+	}
+	if policy.CoolDown <= 0 {
+		policy.CoolDown = time.Second
+	}
+	if policy.TrialCalls < 1 {
+		policy.TrialCalls = 3
+	}
+	return &CircuitBreaker{
+		policy:   policy,
+		outcomes: make([]bool, policy.Window),
+		changed:  make(chan struct{}),
+	}
+}
+
+// 'allow' asks to make a call, it returns 'ErrCircuitOpen' when the call is refused
+// an open breaker turns half-open at the end of the cool-down (or on the first call after it)
+// the generation it returns is passed back to 'done' with the outcome of the call
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.policy.CoolDown {
+		b.trials, b.passed = 0, 0
+		b.change(BreakerHalfOpen)
+	}
+
+	switch {
+	case b.state == BreakerOpen:
+		b.rejected++
+		return b.generation, ErrCircuitOpen
+	case b.state == BreakerHalfOpen && b.trials >= b.policy.TrialCalls:
+		// every trial call is in flight (or done and the breaker is about to close)
+		b.rejected++
+		return b.generation, ErrCircuitOpen
+	case b.state == BreakerHalfOpen:
+		b.trials++
+	}
+	b.allowed++
+	return b.generation, nil
+}
+
+// 'done' records the outcome of a call 'allow' let through
+func (b *CircuitBreaker) done(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		// the call was let through before the last state change, its outcome no longer matters
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		// the call did not reach a verdict, a trial call is handed back
+		if b.state == BreakerHalfOpen {
+			b.trials--
+			b.notify()
+		}
+		return
+	}
+	failed := errors.Is(err, errPanicked) || err != nil && (b.policy.IsFailure == nil || b.policy.IsFailure(err))
+
+	switch b.state {
+	case BreakerClosed:
+		if b.calls == len(b.outcomes) {
+			// the window is full, the oldest outcome drops out
+			if b.outcomes[b.next] {
+				b.failures--
+			}
+		} else {
+			b.calls++
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+		if failed {
+			b.failures++
+		}
+		if b.calls >= b.policy.MinCalls && b.rate() >= b.policy.FailureRate {
+			b.open()
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		b.passed++
+		if b.passed >= b.policy.TrialCalls {
+			b.change(BreakerClosed)
+		}
+	}
+}
+
+// 'open' starts a cool-down, the window starts empty once the breaker closes again
+// the breaker turns half-open when the cool-down ends, so the parked tasks wake up without a call
+func (b *CircuitBreaker) open() {
+	b.openedAt = time.Now()
+	b.change(BreakerOpen)
+
+	clear(b.outcomes)
+	b.next, b.calls, b.failures = 0, 0, 0
+
+	generation := b.generation
+	time.AfterFunc(b.policy.CoolDown, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// a call after the cool-down may have moved the breaker on already
+		if b.generation == generation {
+			b.trials, b.passed = 0, 0
+			b.change(BreakerHalfOpen)
+		}
+	})
+}
+
+func (b *CircuitBreaker) rate() float64 {
+	if b.calls == 0 {
+		return 0
+	}
+	return float64(b.failures) / float64(b.calls)
+}
+
+// 'change' records, logs and reports a state change, called with 'mu' held
+func (b *CircuitBreaker) change(to BreakerState) {
+	change := BreakerChange{Time: time.Now(), From: b.state, To: to, FailureRate: b.rate()}
+	b.state = to
+	b.generation++
+	b.changes = append(b.changes, change)
+	b.notify()
+
+	if b.policy.Logger != nil {
+		if change.From == BreakerClosed {
+			b.policy.Logger.Printf("circuit breaker %v -> %v (failure rate %.0f%%)", change.From, change.To, 100*change.FailureRate)
+		} else {
+			b.policy.Logger.Printf("circuit breaker %v -> %v", change.From, change.To)
+		}
+	}
+	if b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(change)
+	}
+}
+
+// 'notify' wakes everyone waiting on 'refusing', called with 'mu' held
+func (b *CircuitBreaker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// 'refusing' returns a channel which is closed on the next state change while the breaker refuses every call
+// (open and cooling down, or half-open with every trial call in flight), nil once a call may go through
+func (b *CircuitBreaker) refusing() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.state == BreakerOpen && time.Since(b.openedAt) < b.policy.CoolDown:
+		return b.changed
+	case b.state == BreakerHalfOpen && b.trials >= b.policy.TrialCalls:
+		return b.changed
+	}
+	return nil
+}
+
+// 'State' is the current state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// 'Stats' returns the state, the allowed and rejected calls and the state changes so far
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStats{
+		State:    b.state,
+		Allowed:  b.allowed,
+		Rejected: b.rejected,
+		Changes:  append([]BreakerChange(nil), b.changes...),
+	}
+}
+
+// 'WithCircuitBreaker' makes every task attempt (retries included) ask 'breaker' first
+// 'OnStateChange' is called with the breaker lock held, so it must not call back into the breaker
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(c *config) {
+		c.breaker = breaker
+	}
+}
+
+// 'requeue' reports whether a result is a task refused by the breaker which should be put back
+func (p *Pool[T, R]) requeue(result Result[T, R]) bool {
+	breaker := p.config.breaker
+	if breaker == nil || breaker.policy.OnOpen != BreakerRequeue {
+		return false
+	}
+	return result.Status == Failed && errors.Is(result.Err, ErrCircuitOpen)
+}
+
+// 'park' puts aside a task the breaker refused with 'BreakerRequeue' until the breaker lets calls through again
+// parked tasks are counted so the workers do not exit after 'Close' while one is still waiting
+// one goroutine ('wakeParked') waits for the breaker on behalf of every parked task
+func (p *Pool[T, R]) park(j job[T]) {
+	p.mu.Lock()
+	if p.parked.Add(1) == 1 {
+		p.unparked = make(chan struct{})
+	}
+	p.report.Requeued++
+	p.parkedJobs = append(p.parkedJobs, j)
+	wake := !p.waking
+	p.waking = true
+	p.mu.Unlock()
+
+	if wake {
+		go p.wakeParked()
+	}
+}
+
+// 'wakeParked' queues the parked tasks again on each state change of the breaker which lets calls through
+// a stopped pool gets the tasks back right away, they are reported as 'NotStarted'
+// tasks refused again (e.g. more tasks than half-open trial calls) are parked again for the next change
+func (p *Pool[T, R]) wakeParked() {
+	for {
+		if refusing := p.config.breaker.refusing(); refusing != nil {
+			select {
+			case <-refusing:
+				continue
+			case <-p.ctx.Done():
+			}
+		}
+
+		p.mu.Lock()
+		parked := p.parkedJobs
+		p.parkedJobs = nil
+		if len(parked) == 0 {
+			p.waking = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		for _, j := range parked {
+			if p.steal != nil {
+				p.steal.requeue(j)
+				p.unpark()
+				p.steal.signal(true)
+				continue
+			}
+			p.requeued <- j
+			p.unpark()
+		}
+	}
+}
+
+// 'unpark' counts a parked task handed back to the workers, the last one closes 'unparked'
+func (p *Pool[T, R]) unpark() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.parked.Add(-1) == 0 {
+		close(p.unparked)
+	}
+}
+
+// 'parkedJob' waits for a parked task once the buffered channel is closed, false once none is parked
+func (p *Pool[T, R]) parkedJob() (job[T], bool) {
+	p.mu.Lock()
+	unparked := p.unparked
+	p.mu.Unlock()
+
+	select {
+	case j := <-p.requeued:
+		return j, true
+	case <-unparked:
+		var zero job[T]
+		return zero, false
+	}
+}
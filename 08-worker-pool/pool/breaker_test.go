@@ -0,0 +1,231 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 'waitForChange' waits for the next state change of 'b' while it refuses every call
+func waitForChange(t *testing.T, b *CircuitBreaker) {
+	t.Helper()
+	refusing := b.refusing()
+	if refusing == nil {
+		t.Fatalf("the %v breaker lets calls through", b.State())
+	}
+	select {
+	case <-refusing:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the breaker stayed %v", b.State())
+	}
+}
+
+// 'call' is one call through the breaker which fails with 'err'
+func call(b *CircuitBreaker, err error) error {
+	generation, allowErr := b.allow()
+	if allowErr != nil {
+		return allowErr
+	}
+	b.done(generation, err)
+	return nil
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	errDown := errors.New("down")
+
+	var mu sync.Mutex
+	var changes []string
+	b := NewCircuitBreaker(BreakerPolicy{Window: 4, MinCalls: 4, CoolDown: 20 * time.Millisecond, TrialCalls: 2,
+		OnStateChange: func(change BreakerChange) {
+			mu.Lock()
+			changes = append(changes, change.From.String()+" -> "+change.To.String())
+			mu.Unlock()
+		}})
+
+	// 2 of the 4 calls of the window failed: the breaker opens and refuses calls
+	for _, err := range []error{nil, errDown, nil, errDown} {
+		if err := call(b, err); err != nil {
+			t.Fatalf("a closed breaker refused a call: %v", err)
+		}
+	}
+	if err := call(b, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v from an open breaker, want ErrCircuitOpen", err)
+	}
+
+	// the cool-down ends without a call, 2 trial calls go through, a third one waits for them
+	waitForChange(t, b)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("the breaker is %v after the cool-down, want half-open", state)
+	}
+	first, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v with every trial in flight, want ErrCircuitOpen", err)
+	}
+
+	// a failed trial opens the breaker again, the other trial no longer counts
+	b.done(first, errDown)
+	b.done(second, nil)
+	waitForChange(t, b)
+
+	// 2 trials which succeed close it
+	for i := 0; i < 2; i++ {
+		if err := call(b, nil); err != nil {
+			t.Fatalf("trial %v: %v", i, err)
+		}
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("the breaker is %v after the trials, want closed", state)
+	}
+
+	want := []string{"closed -> open", "open -> half-open", "half-open -> open", "open -> half-open", "half-open -> closed"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("got changes %v, want %v", changes, want)
+		}
+	}
+	if stats := b.Stats(); stats.Allowed != 8 || stats.Rejected != 2 {
+		t.Errorf("got %v allowed and %v rejected calls, want 8 and 2", stats.Allowed, stats.Rejected)
+	}
+}
+
+func TestCircuitBreakerFailures(t *testing.T) {
+	errNotFound := errors.New("not found")
+	isFailure := func(err error) bool { return !errors.Is(err, errNotFound) }
+
+	tests := []struct {
+		name     string
+		err      error
+		wantOpen bool
+	}{
+		{"an error", errors.New("down"), true},
+		{"an error 'IsFailure' ignores", errNotFound, false},
+		{"the pool stopping", context.Canceled, false},
+		{"a panic", errPanicked, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewCircuitBreaker(BreakerPolicy{Window: 2, MinCalls: 2, IsFailure: isFailure})
+			call(b, test.err)
+			call(b, test.err)
+			if open := b.State() == BreakerOpen; open != test.wantOpen {
+				t.Errorf("the breaker is %v, want open %v", b.State(), test.wantOpen)
+			}
+		})
+	}
+}
+
+// a half-open trial which panics opens the breaker again, it used to stay half-open and refuse every call
+func TestCircuitBreakerTrialPanic(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerPolicy{Window: 2, MinCalls: 2, CoolDown: 20 * time.Millisecond, TrialCalls: 1})
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		switch {
+		case data < 0:
+			return 0, errors.New("down")
+		case data == 0:
+			panic("boom")
+		}
+		return data, nil
+	}, WithCircuitBreaker(breaker), WithPanicPolicy(PanicPolicy{MaxPanics: 1}))
+	defer p.Wait()
+	defer drain(p)
+	defer p.Close()
+
+	next := func(data int) Result[int, int] {
+		t.Helper()
+		p.Submit(data)
+		select {
+		case result := <-p.Results():
+			return result
+		case <-time.After(5 * time.Second):
+			t.Fatalf("task %v never finished", data)
+		}
+		return Result[int, int]{}
+	}
+
+	next(-1)
+	next(-2)
+	waitForChange(t, breaker)
+	if result := next(0); result.Status != Quarantined {
+		t.Fatalf("the trial ended %v, want quarantined", result.Status)
+	}
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("the breaker is %v after the trial panicked, want open", state)
+	}
+	waitForChange(t, breaker)
+	if result := next(1); result.Status != Succeeded {
+		t.Fatalf("the next trial ended %v (%v), want succeeded", result.Status, result.Err)
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Errorf("the breaker is %v, want closed", state)
+	}
+}
+
+// with 'BreakerRequeue' the tasks an open breaker refused are parked and all of them run once it closes
+func TestCircuitBreakerRequeue(t *testing.T) {
+	const numTasks = 200
+
+	for _, scheduler := range []Scheduler{ChannelScheduler, WorkStealing} {
+		t.Run(scheduler.String(), func(t *testing.T) {
+			breaker := NewCircuitBreaker(BreakerPolicy{Window: 10, MinCalls: 10, CoolDown: 20 * time.Millisecond, TrialCalls: 2, OnOpen: BreakerRequeue})
+
+			// the downstream is down for the first 50ms
+			down := time.Now().Add(50 * time.Millisecond)
+			p := New(8, func(ctx context.Context, data int) (int, error) {
+				time.Sleep(time.Millisecond)
+				if time.Now().Before(down) {
+					return 0, errors.New("down")
+				}
+				return data, nil
+			}, WithCircuitBreaker(breaker), WithScheduler(scheduler), WithRetry(RetryPolicy{MaxAttempts: 100, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+
+			go func() {
+				defer p.Close()
+				for i := 0; i < numTasks; i++ {
+					p.Submit(i)
+				}
+			}()
+
+			finished := make(chan map[int]int)
+			go func() {
+				ran := make(map[int]int)
+				for result := range p.Results() {
+					if result.Status != Succeeded {
+						t.Errorf("task %v ended %v: %v", result.Task, result.Status, result.Err)
+					}
+					ran[result.Value]++
+				}
+				finished <- ran
+			}()
+
+			select {
+			case ran := <-finished:
+				for i := 0; i < numTasks; i++ {
+					if ran[i] != 1 {
+						t.Errorf("task %v ran %v times, want 1", i, ran[i])
+					}
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("the pool hung with %v tasks parked and the breaker %v", p.parked.Load(), breaker.State())
+			}
+			p.Wait()
+
+			if report := p.Report(); report.Requeued == 0 {
+				t.Error("no task was requeued")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// a 'Timetable' (see 'schedule.go') waits on timers, a test of "run this task in an hour" should not wait an hour
+// it reads the time through a 'Clock': 'RealClock' is the 'time' package, a 'FakeClock' only moves on 'Advance'
+
+// 'Clock' is the time source of a 'Timetable'
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) ClockTimer
+}
+
+// 'ClockTimer' is a one-shot timer of a 'Clock', its channel receives the time once 'd' has passed
+type ClockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// 'RealClock' is the wall clock
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) NewTimer(d time.Duration) ClockTimer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+
+func (t realTimer) Stop() bool { return t.timer.Stop() }
+
+// 'FakeClock' is a clock for tests, its time only moves on 'Advance' (or 'Set')
+// a timer fires during the 'Advance' which reaches its deadline, a timer of 0 or less fires right away
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// 'armed' is signalled on every 'NewTimer' so 'WaitForTimers' does not poll
+	armed *sync.Cond
+}
+
+// 'NewFakeClock' returns a clock standing at 'now'
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.armed = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.armed.Broadcast()
+	return t
+}
+
+// 'Advance' moves the clock forward by 'd' and fires every timer whose deadline has passed, earliest first
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// 'Set' moves the clock to 'now' (forward only) and fires the timers like 'Advance'
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.After(c.now) {
+		c.set(now)
+	}
+}
+
+func (c *FakeClock) set(now time.Time) {
+	c.now = now
+	sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+	fired := 0
+	for _, t := range c.timers {
+		if t.deadline.After(now) {
+			break
+		}
+		t.c <- now
+		fired++
+	}
+	c.timers = c.timers[fired:]
+}
+
+// 'Timers' is the number of timers waiting for their deadline
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// 'WaitForTimers' blocks until at least 'n' timers wait for their deadline
+// a test calls it before 'Advance' so the code under test has armed its timer for the time it is advanced past
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.armed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
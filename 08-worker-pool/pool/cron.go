@@ -0,0 +1,202 @@
+package pool
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a cron spec is the 5 fields of a crontab line: minute hour day-of-month month day-of-week
+// -each field is '*', a value, a range '1-5', a step '*/15' or '10-50/20', or a list of those '0,30'
+// -day-of-week is 0-6 from Sunday (7 is Sunday too), months and weekdays may be named: 'jan', 'mon'
+// -when both day fields are restricted a day matching either one runs ('0 0 1 * mon' = the 1st and every Monday)
+// -a day field starting with '*' ('*', '*/2') is unrestricted as in Vixie cron, so '0 0 */2 * mon' = the odd days which are Mondays
+// -'@hourly', '@daily' ('@midnight'), '@weekly', '@monthly' and '@yearly' ('@annually') are the usual shortcuts
+// -'@every 90s' runs every 'time.ParseDuration' interval, counted from the time it was scheduled
+// the times are in the location of the clock ('time.Local' with 'RealClock')
+
+// 'CronSchedule' is a parsed cron spec
+type CronSchedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+	every                         time.Duration
+}
+
+// 'cronField' is the range and the names of one field
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// 'ParseCron' parses a cron spec, see above
+func ParseCron(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	schedule := &CronSchedule{spec: spec}
+
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("pool: cron spec %q: %w", spec, err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("pool: cron spec %q: the interval is shorter than 1s", spec)
+		}
+		schedule.every = every
+		return schedule, nil
+	}
+
+	fields := strings.Fields(spec)
+	if shortcut, ok := cronShortcuts[spec]; ok {
+		fields = strings.Fields(shortcut)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("pool: cron spec %q: want 5 fields, got %v", spec, len(fields))
+	}
+
+	masks := [5]*uint64{&schedule.minute, &schedule.hour, &schedule.dom, &schedule.month, &schedule.dow}
+	for i, field := range fields {
+		mask, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("pool: cron spec %q: %v: %w", spec, cronFields[i].name, err)
+		}
+		*masks[i] = mask
+	}
+	// 7 is Sunday too
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+// 'parse' returns the bit mask of the values a field matches
+func (f cronField) parse(field string) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step %q", stepPart)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(first); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(last); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// '10/20' is '10-max/20'
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("bad range %q", rangePart)
+			}
+		}
+		for v := low; v <= high; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// 'value' is a number or a name of the field
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("bad value %q (want %v-%v)", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// 'Next' is the first time after 't' the schedule runs (the zero time if it never does, e.g. '0 0 30 2 *')
+// the search moves a field at a time: a month that does not match skips to the next month, and so on down to minutes
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.Add(c.every)
+	}
+
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	// every valid spec runs within 8 years (a 29th of February on a given weekday)
+	limit := t.Year() + 8
+
+	for t.Year() <= limit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			// jump straight to the next matching minute of the hour, or to the next hour
+			rest := c.minute >> uint(t.Minute())
+			if rest == 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			} else {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// 'dayMatches' is the day rule of cron: with both day fields restricted either one may match
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (c *CronSchedule) String() string {
+	return c.spec
+}
@@ -0,0 +1,393 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// a 'Pool' treats every task as independent, but API calls often depend on each other
+// (fetch a token first, then the pages with that token, then a summary of the pages)
+// a 'DAG' (directed acyclic graph) runs tasks which declare the ids of the tasks they depend on:
+// -a task is sent to the pool only once every task it depends on has succeeded
+// -a task whose dependency did not succeed is not run, it is reported as 'Skipped' (and so are its own dependents)
+// -a dependency may be submitted after its dependents, but a submission that would close a cycle is refused
+// a journal ('WithJournal') is not supported, the pool behind a 'DAG' runs its own task type
+
+var (
+	// 'ErrCycle' is returned by 'DAG.Submit' when the task would depend on itself (directly or through other tasks)
+	ErrCycle = errors.New("pool: dependency cycle")
+	// 'ErrDependencyFailed' is the error of a 'Skipped' task
+	ErrDependencyFailed = errors.New("pool: dependency did not succeed")
+	// 'ErrMissingDependency' is the error of a task skipped because a dependency was never submitted before 'Close'
+	ErrMissingDependency = errors.New("pool: dependency never submitted")
+)
+
+// 'DAGResult' is a 'Result' with the id the task was submitted with
+type DAGResult[K comparable, T, R any] struct {
+	ID K
+	Result[T, R]
+}
+
+// 'dagTask' is what the pool behind a 'DAG' runs
+type dagTask[K comparable, T any] struct {
+	id   K
+	data T
+}
+
+// 'dagState' is where a task is in the graph
+type dagState int
+
+const (
+	// waiting for its dependencies, or not submitted yet (only depended on)
+	dagWaiting dagState = iota
+	// sent to the pool
+	dagRunning
+	dagSucceeded
+	// failed, cancelled, never started, quarantined, skipped or missing
+	dagFailed
+)
+
+// 'dagNode' is one task id of the graph
+// 'children' are the tasks depending on it, 'pending' is the number of its dependencies that have not succeeded yet
+type dagNode[K comparable, T any] struct {
+	id        K
+	data      T
+	submitted bool
+	state     dagState
+	pending   int
+	children  []*dagNode[K, T]
+}
+
+// 'DAG' runs tasks with dependencies on a 'Pool'
+// 'Submit()' tasks with their dependencies, 'Close()' when there are no more, read 'Results()' and 'Wait()'
+type DAG[K comparable, T, R any] struct {
+	pool    *Pool[dagTask[K, T], R]
+	results chan DAGResult[K, T, R]
+
+	// 'ready' tasks are fed to the pool by one goroutine, 'out' results are sent to 'results' by another
+	// so neither a full pool nor a slow reader of 'Results()' ever blocks 'Submit' or the completion of a task
+	mu         sync.Mutex
+	nodes      map[K]*dagNode[K, T]
+	ready      []dagTask[K, T]
+	readyCond  *sync.Cond
+	out        []DAGResult[K, T, R]
+	outCond    *sync.Cond
+	unresolved int
+	skipped    int
+	refused    int
+	closed     bool
+	drained    bool
+	collected  bool
+}
+
+// 'NewDAG' starts a pool of 'numberOfWorkers' running 'task' (see 'NewContext') for the tasks of a graph
+func NewDAG[K comparable, T, R any](ctx context.Context, numberOfWorkers int, task Task[T, R], options ...Option) *DAG[K, T, R] {
+	d := &DAG[K, T, R]{
+		pool: NewContext(ctx, numberOfWorkers, func(ctx context.Context, t dagTask[K, T]) (R, error) {
+			return task(ctx, t.data)
+		}, options...),
+		results: make(chan DAGResult[K, T, R], numberOfWorkers),
+		nodes:   make(map[K]*dagNode[K, T]),
+	}
+	d.readyCond = sync.NewCond(&d.mu)
+	d.outCond = sync.NewCond(&d.mu)
+
+	go d.feed()
+	go d.collect()
+	go d.emit()
+
+	return d
+}
+
+// 'Submit' adds task 'id' which runs once every task in 'dependsOn' has succeeded
+// it returns 'ErrCycle' when one of 'dependsOn' already depends on 'id', the task is not added then
+// submitting an id twice is an error, submitting after 'Close' returns 'ErrStopped'
+func (d *DAG[K, T, R]) Submit(id K, data T, dependsOn ...K) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrStopped
+	}
+	node := d.nodes[id]
+	if node != nil && node.submitted {
+		return fmt.Errorf("pool: task %v submitted twice", id)
+	}
+	for _, dep := range dependsOn {
+		if path := d.path(id, dep); path != nil {
+			return fmt.Errorf("%w: %v", ErrCycle, formatCycle(path))
+		}
+	}
+
+	if node == nil {
+		node = &dagNode[K, T]{id: id}
+		d.nodes[id] = node
+	}
+	node.data = data
+	node.submitted = true
+	d.unresolved++
+
+	var failed *dagNode[K, T]
+	for _, dep := range dependsOn {
+		parent := d.nodes[dep]
+		if parent == nil {
+			// depended on before it is submitted
+			parent = &dagNode[K, T]{id: dep}
+			d.nodes[dep] = parent
+		}
+		switch parent.state {
+		case dagSucceeded:
+		case dagFailed:
+			failed = parent
+		default:
+			node.pending++
+			parent.children = append(parent.children, node)
+		}
+	}
+
+	switch {
+	case failed != nil:
+		d.skip(node, fmt.Errorf("%w: %v", ErrDependencyFailed, failed.id))
+	case node.pending == 0:
+		d.start(node)
+	}
+	return nil
+}
+
+// 'path' returns the chain of dependents from 'from' to 'to' (nil when 'to' does not depend on 'from')
+// if 'to' depends on 'from', 'from' depending on 'to' would close a cycle
+func (d *DAG[K, T, R]) path(from, to K) []K {
+	if from == to {
+		return []K{from}
+	}
+	node := d.nodes[from]
+	if node == nil {
+		return nil
+	}
+
+	// depth first over the children, 'seen' stops at tasks reached through another path
+	seen := make(map[K]bool)
+	var visit func(n *dagNode[K, T]) []K
+	visit = func(n *dagNode[K, T]) []K {
+		if n.id == to {
+			return []K{n.id}
+		}
+		if seen[n.id] {
+			return nil
+		}
+		seen[n.id] = true
+		for _, child := range n.children {
+			if rest := visit(child); rest != nil {
+				return append([]K{n.id}, rest...)
+			}
+		}
+		return nil
+	}
+	return visit(node)
+}
+
+// 'formatCycle' writes the cycle closed by the submitted task as "a -> b -> a" ("->" reads "depends on")
+// 'path' runs from the submitted task to the dependency through dependents, so it is read backwards
+func formatCycle[K comparable](path []K) string {
+	ids := make([]string, 0, len(path)+1)
+	ids = append(ids, fmt.Sprint(path[0]))
+	for i := len(path) - 1; i >= 0; i-- {
+		ids = append(ids, fmt.Sprint(path[i]))
+	}
+	return strings.Join(ids, " -> ")
+}
+
+// 'start' queues a task for the pool (called with 'mu' held)
+func (d *DAG[K, T, R]) start(node *dagNode[K, T]) {
+	node.state = dagRunning
+	d.ready = append(d.ready, dagTask[K, T]{id: node.id, data: node.data})
+	d.readyCond.Signal()
+}
+
+// 'resolve' records the result of a task that ran and releases or skips its dependents (called with 'mu' held)
+func (d *DAG[K, T, R]) resolve(node *dagNode[K, T], result Result[T, R]) {
+	d.unresolved--
+	d.output(DAGResult[K, T, R]{ID: node.id, Result: result})
+
+	if result.Status != Succeeded {
+		node.state = dagFailed
+		d.skipChildren(node, fmt.Errorf("%w: %v", ErrDependencyFailed, node.id))
+		d.checkDone()
+		return
+	}
+
+	node.state = dagSucceeded
+	for _, child := range node.children {
+		child.pending--
+		if child.pending == 0 && child.state == dagWaiting {
+			d.start(child)
+		}
+	}
+	node.children = nil
+	d.checkDone()
+}
+
+// 'skip' reports a submitted task as 'Skipped' and skips its dependents too (called with 'mu' held)
+func (d *DAG[K, T, R]) skip(node *dagNode[K, T], err error) {
+	node.state = dagFailed
+	d.unresolved--
+	d.skipped++
+	d.output(DAGResult[K, T, R]{ID: node.id, Result: Result[T, R]{Task: node.data, Err: err, Status: Skipped}})
+	d.skipChildren(node, fmt.Errorf("%w: %v", ErrDependencyFailed, node.id))
+	d.checkDone()
+}
+
+// 'skipChildren' skips the dependents of a task that did not succeed
+// a dependent already skipped through another dependency is reported once
+func (d *DAG[K, T, R]) skipChildren(node *dagNode[K, T], err error) {
+	children := node.children
+	node.children = nil
+	for _, child := range children {
+		if child.state == dagWaiting {
+			d.skip(child, err)
+		}
+	}
+}
+
+// 'output' queues a result for 'Results()' (called with 'mu' held)
+func (d *DAG[K, T, R]) output(result DAGResult[K, T, R]) {
+	d.out = append(d.out, result)
+	d.outCond.Signal()
+}
+
+// 'checkDone' closes the pool once the graph is closed and every submitted task is resolved (called with 'mu' held)
+func (d *DAG[K, T, R]) checkDone() {
+	if d.closed && d.unresolved == 0 && !d.drained {
+		d.drained = true
+		d.readyCond.Signal()
+	}
+}
+
+// 'Close' stops accepting tasks, tasks depending on an id that was never submitted are skipped
+// the pool is closed once every remaining task has run or been skipped
+func (d *DAG[K, T, R]) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, node := range d.nodes {
+		if !node.submitted && node.state == dagWaiting {
+			node.state = dagFailed
+			d.skipChildren(node, fmt.Errorf("%w: %v", ErrMissingDependency, node.id))
+		}
+	}
+	d.checkDone()
+}
+
+// 'feed' submits the ready tasks to the pool, then closes it
+// a task the pool does not accept (it stopped) is resolved as 'NotStarted'
+func (d *DAG[K, T, R]) feed() {
+	for {
+		d.mu.Lock()
+		for len(d.ready) == 0 && !d.drained {
+			d.readyCond.Wait()
+		}
+		if len(d.ready) == 0 {
+			d.mu.Unlock()
+			d.pool.Close()
+			return
+		}
+		task := d.ready[0]
+		d.ready = d.ready[1:]
+		d.mu.Unlock()
+
+		if err := d.pool.Submit(task); err != nil {
+			d.mu.Lock()
+			d.refused++
+			d.resolve(d.nodes[task.id], Result[T, R]{Task: task.data, Err: err, Status: NotStarted})
+			d.mu.Unlock()
+		}
+	}
+}
+
+// 'collect' reads the pool results and resolves their tasks
+func (d *DAG[K, T, R]) collect() {
+	for result := range d.pool.Results() {
+		d.mu.Lock()
+		d.resolve(d.nodes[result.Task.id], Result[T, R]{
+			Task:     result.Task.data,
+			Value:    result.Value,
+			Err:      result.Err,
+			Status:   result.Status,
+			Attempts: result.Attempts,
+			Priority: result.Priority,
+			Wait:     result.Wait,
+			Latency:  result.Latency,
+		})
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	d.collected = true
+	d.outCond.Signal()
+	d.mu.Unlock()
+}
+
+// 'emit' sends the queued results to 'results' and closes it after the last one
+func (d *DAG[K, T, R]) emit() {
+	defer close(d.results)
+	for {
+		d.mu.Lock()
+		for len(d.out) == 0 && !d.collected {
+			d.outCond.Wait()
+		}
+		if len(d.out) == 0 {
+			d.mu.Unlock()
+			return
+		}
+		result := d.out[0]
+		d.out = d.out[1:]
+		d.mu.Unlock()
+
+		d.results <- result
+	}
+}
+
+// 'Results' is the results channel, one value per submitted task (run or skipped) in completion order
+func (d *DAG[K, T, R]) Results() <-chan DAGResult[K, T, R] {
+	return d.results
+}
+
+// 'Wait' blocks until the pool behind the graph has stopped, it returns the pool's 'Wait' error
+func (d *DAG[K, T, R]) Wait() error {
+	return d.pool.Wait()
+}
+
+// 'Report' is the pool 'Report' plus the number of 'Skipped' tasks
+// tasks the pool no longer accepted (it stopped) are counted as 'NotStarted'
+func (d *DAG[K, T, R]) Report() Report[T] {
+	inner := d.pool.Report()
+
+	report := Report[T]{
+		Succeeded:   inner.Succeeded,
+		Failed:      inner.Failed,
+		Cancelled:   inner.Cancelled,
+		NotStarted:  inner.NotStarted,
+		Quarantined: inner.Quarantined,
+		Retries:     inner.Retries,
+		Latency:     inner.Latency,
+	}
+	for _, failure := range inner.Failures {
+		report.Failures = append(report.Failures, Failure[T]{Task: failure.Task.data, Err: failure.Err, Attempts: failure.Attempts})
+	}
+	for _, panicked := range inner.Panics {
+		report.Panics = append(report.Panics, PanicRecord[T]{Task: panicked.Task.data, Value: panicked.Value, Stack: panicked.Stack, Time: panicked.Time})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	report.Skipped = d.skipped
+	report.NotStarted += d.refused
+	return report
+}
@@ -0,0 +1,159 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDAGCycle(t *testing.T) {
+	// each submission is "id:dependency,dependency", only the last one closes a cycle (or not, 'want' is "")
+	tests := []struct {
+		name        string
+		submissions []string
+		want        string
+	}{
+		{"a task depending on itself", []string{"a:a"}, "a -> a"},
+		{"two tasks depending on each other", []string{"a:b", "b:a"}, "b -> a -> b"},
+		{"a cycle through three tasks", []string{"a:b", "b:c", "c:a"}, "c -> a -> b -> c"},
+		{"a diamond is no cycle", []string{"b:a", "c:a", "d:b,c", "a:"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDAG[string](context.Background(), 2, func(ctx context.Context, data string) (string, error) {
+				return data, nil
+			})
+			var err error
+			for _, submission := range test.submissions {
+				id, deps, _ := strings.Cut(submission, ":")
+				var dependsOn []string
+				if deps != "" {
+					dependsOn = strings.Split(deps, ",")
+				}
+				err = d.Submit(id, id, dependsOn...)
+			}
+
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("got %v, want no cycle", err)
+			case test.want != "" && (!errors.Is(err, ErrCycle) || !strings.HasSuffix(err.Error(), test.want)):
+				t.Errorf("got %v, want the cycle %v", err, test.want)
+			}
+
+			// the refused task is not part of the graph, the others are skipped or run
+			d.Close()
+			results := 0
+			for range d.Results() {
+				results++
+			}
+			d.Wait()
+			wantResults := len(test.submissions)
+			if test.want != "" {
+				wantResults--
+			}
+			if results != wantResults {
+				t.Errorf("got %v results, want %v", results, wantResults)
+			}
+		})
+	}
+}
+
+// every task starts after the tasks it depends on finished, the dependents are submitted before their dependencies
+func TestDAGOrder(t *testing.T) {
+	dependsOn := map[string][]string{
+		"summary": {"page1", "page2", "page3"},
+		"page1":   {"token"},
+		"page2":   {"token"},
+		"page3":   {"token", "page1"},
+		"token":   nil,
+	}
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	d := NewDAG[string](context.Background(), 4, func(ctx context.Context, id string) (string, error) {
+		mu.Lock()
+		for _, dep := range dependsOn[id] {
+			if !finished[dep] {
+				t.Errorf("%v started before %v finished", id, dep)
+			}
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		finished[id] = true
+		mu.Unlock()
+		return id, nil
+	})
+
+	for _, id := range []string{"summary", "page3", "page2", "page1", "token"} {
+		if err := d.Submit(id, id, dependsOn[id]...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Close()
+
+	var order []string
+	for result := range d.Results() {
+		if result.Status != Succeeded || result.Value != result.ID {
+			t.Errorf("%v ended %v: %v", result.ID, result.Status, result.Err)
+		}
+		order = append(order, result.ID)
+	}
+	d.Wait()
+	if len(order) != 5 || order[0] != "token" || order[4] != "summary" {
+		t.Errorf("finished in the order %v, want token first and summary last", order)
+	}
+}
+
+// a task which failed skips its dependents and theirs, a dependency never submitted skips its dependents at 'Close'
+func TestDAGSkip(t *testing.T) {
+	d := NewDAG[string](context.Background(), 2, func(ctx context.Context, id string) (string, error) {
+		if id == "b" {
+			return "", errors.New("down")
+		}
+		return id, nil
+	})
+	d.Submit("a", "a")
+	d.Submit("b", "b", "a")
+	d.Submit("c", "c", "b")
+	d.Submit("d", "d", "c")
+	d.Submit("e", "e", "a")
+	d.Submit("f", "f", "missing")
+	if err := d.Submit("a", "a"); err == nil {
+		t.Error("a task was submitted twice")
+	}
+	d.Close()
+	if err := d.Submit("g", "g"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Submit after 'Close' returned %v, want ErrStopped", err)
+	}
+
+	want := map[string]struct {
+		status Status
+		err    error
+	}{
+		"a": {Succeeded, nil},
+		"b": {Failed, nil},
+		"c": {Skipped, ErrDependencyFailed},
+		"d": {Skipped, ErrDependencyFailed},
+		"e": {Succeeded, nil},
+		"f": {Skipped, ErrMissingDependency},
+	}
+	for result := range d.Results() {
+		w := want[result.ID]
+		if result.Status != w.status || w.err != nil && !errors.Is(result.Err, w.err) {
+			t.Errorf("%v ended %v: %v, want %v: %v", result.ID, result.Status, result.Err, w.status, w.err)
+		}
+		delete(want, result.ID)
+	}
+	d.Wait()
+	if len(want) > 0 {
+		t.Errorf("no result for %v", want)
+	}
+	if report := d.Report(); report.Succeeded != 2 || report.Failed != 1 || report.Skipped != 3 {
+		t.Errorf("the report counts %v succeeded, %v failed and %v skipped, want 2, 1 and 3", report.Succeeded, report.Failed, report.Skipped)
+	}
+}
@@ -0,0 +1,303 @@
+package pool
+
+import (
+	"context"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// a few slow calls (stragglers) decide when a run ends: 99 calls take 100ms, the 100th takes 2s
+// hedging sends a second copy of a call that has not finished after a delay, the first copy to succeed wins
+// and the other one is cancelled, a straggler then takes about the delay plus a normal call
+// -the delay is fixed ('Delay') or a percentile of the recent call latency ('Percentile'), so only the slowest calls are hedged
+// -at most 'MaxRatio' of the calls are hedged, a slow downstream (every call slow) does not get twice the load
+// the task must be safe to run twice at the same time (idempotent), the loser sees 'ctx.Done()'
+// each copy is a call of its own: it is let through by the circuit breaker, takes a token of the rate limiter
+// and a slot of the adaptive limiter, the cancelled loser is not recorded as a failure there
+
+// 'HedgePolicy' configures hedging, zero fields get the defaults in brackets
+// -'Delay' hedges a call after this long (0 = use 'Percentile')
+// -'Percentile' hedges a call slower than this percentile of the last 1000 calls (0.95)
+// -'MinDelay' is the lowest percentile delay, calls which all take about the same time are not hedged for jitter (0)
+// -'MinSamples' is the number of calls observed before a percentile delay is used, no call is hedged before (100)
+// -'MaxRatio' is the share of calls which may be hedged, at most 0.5 (0.05)
+type HedgePolicy struct {
+	Delay      time.Duration
+	Percentile float64
+	MinDelay   time.Duration
+	MinSamples int
+	MaxRatio   float64
+}
+
+// 'HedgeStats' is how many calls were made, how many were hedged and how many the hedge won
+// 'Saved' estimates the latency the winning hedges saved: for each one the average of the recent calls slower
+// than the call took with the hedge (what the cancelled copy would likely have taken), minus that time
+// it is estimated from the calls which were not hedged (0 while none was that slow): for the last 1000 wins
+// when 'HedgeStats' is called, for older wins when they dropped out of that window
+// 'Delay' is the current hedge delay (0 while there are too few samples for a percentile)
+type HedgeStats struct {
+	Calls  int
+	Hedged int
+	Won    int
+	Denied int
+	Saved  time.Duration
+	Delay  time.Duration
+}
+
+// 'hedgeSamples' is how many recent call latencies the percentile is computed over,
+// and how many recent wins 'Saved' is estimated for when 'HedgeStats' is called
+const hedgeSamples = 1000
+
+// 'hedger' keeps the recent call latencies and the counts of one pool
+type hedger struct {
+	policy HedgePolicy
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	delay   time.Duration
+	stale   int
+	stats   HedgeStats
+	// 'wins' is how long each recent call the hedge won took (a ring as 'samples'),
+	// 'saved' the estimate for the wins which dropped out of it
+	wins    []time.Duration
+	nextWin int
+	saved   time.Duration
+}
+
+func newHedger(policy HedgePolicy) *hedger {
+	if policy.Percentile <= 0 || policy.Percentile >= 1 {
+		policy.Percentile = 0.95
+	}
+	if policy.MinSamples < 1 {
+		policy.MinSamples = 100
+	}
+	if policy.MaxRatio <= 0 {
+		policy.MaxRatio = 0.05
+	}
+	policy.MaxRatio = min(policy.MaxRatio, 0.5)
+	return &hedger{policy: policy, delay: policy.Delay}
+}
+
+// 'start' counts a call and returns its hedge delay, false when there is no delay yet
+func (h *hedger) start() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Calls++
+	return h.delay, h.delay > 0
+}
+
+// 'fire' reports whether a hedge may be sent, at most 'MaxRatio' of the calls are hedged
+func (h *hedger) fire() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if float64(h.stats.Hedged+1) > h.policy.MaxRatio*float64(h.stats.Calls) {
+		h.stats.Denied++
+		return false
+	}
+	h.stats.Hedged++
+	return true
+}
+
+// 'observe' adds a call latency, a percentile delay is computed again every 50 calls
+func (h *hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, latency)
+	} else {
+		h.samples[h.next] = latency
+		h.next = (h.next + 1) % hedgeSamples
+	}
+
+	h.stale++
+	if h.policy.Delay > 0 || len(h.samples) < h.policy.MinSamples || (h.stale < 50 && h.delay > 0) {
+		return
+	}
+	h.stale = 0
+	sorted := append([]time.Duration(nil), h.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	h.delay = max(sorted[int(h.policy.Percentile*float64(len(sorted)-1))], h.policy.MinDelay)
+}
+
+// 'won' records a call the hedge won after 'took', the first copy is cancelled after running that long
+func (h *hedger) won(took time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Won++
+	if len(h.wins) < hedgeSamples {
+		h.wins = append(h.wins, took)
+		return
+	}
+	h.saved += h.saving(h.wins[h.nextWin])
+	h.wins[h.nextWin] = took
+	h.nextWin = (h.nextWin + 1) % hedgeSamples
+}
+
+// 'saving' estimates the latency a win after 'took' saved, called with 'mu' held
+// the first copy would have taken as long as a typical call slower than 'took'
+func (h *hedger) saving(took time.Duration) time.Duration {
+	var sum time.Duration
+	slower := 0
+	for _, latency := range h.samples {
+		if latency > took {
+			sum += latency
+			slower++
+		}
+	}
+	if slower == 0 {
+		return 0
+	}
+	return max(sum/time.Duration(slower)-took, 0)
+}
+
+func (h *hedger) snapshot() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats
+	stats.Delay = h.delay
+	stats.Saved = h.saved
+	for _, took := range h.wins {
+		stats.Saved += h.saving(took)
+	}
+	return stats
+}
+
+// 'hedgePanic' carries a panic of a copy running in its own goroutine to the worker, which panics again with it
+// the worker recovers it like a panic of the task itself (see 'panic.go'), with the stack of the copy
+type hedgePanic struct {
+	value any
+	stack []byte
+}
+
+// 'hedgeOutcome' is the result of one copy of a call
+type hedgeOutcome[R any] struct {
+	value   R
+	err     error
+	hedge   bool
+	panic   *hedgePanic
+	latency time.Duration
+}
+
+// 'hedged' calls the task and, once the hedge delay has passed without a result, a second copy of it
+// the first copy to succeed wins (when both fail the error of the last one is returned), the other is cancelled
+// each copy goes through 'guard', its latency is measured from the call of the task (not the waits before)
+func (p *Pool[T, R]) hedged(data T) (R, error) {
+	h := p.hedge
+	call := func(ctx context.Context) (value R, latency time.Duration, err error) {
+		err = p.guard(ctx, func() error {
+			started := time.Now()
+			value, err = p.callTask(ctx, data)
+			latency = time.Since(started)
+			return err
+		})
+		return value, latency, err
+	}
+
+	delay, ok := h.start()
+	if !ok {
+		value, latency, err := call(p.ctx)
+		if err == nil {
+			h.observe(latency)
+		}
+		return value, err
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	started := time.Now()
+	outcomes := make(chan hedgeOutcome[R], 2)
+	// a copy counts in 'wg', 'Wait' returns once the cancelled loser returned too (and gave its slot back)
+	// a loser which panics once the call returned has nobody to panic again for it, its panic is only recorded
+	var mu sync.Mutex
+	returned := false
+	defer func() {
+		mu.Lock()
+		returned = true
+		mu.Unlock()
+		for {
+			select {
+			case outcome := <-outcomes:
+				if outcome.panic != nil {
+					p.recordPanic(PanicRecord[T]{Task: data, Value: outcome.panic.value, Stack: string(outcome.panic.stack)}, outcome.panic.stack)
+				}
+			default:
+				return
+			}
+		}
+	}()
+	launch := func(hedge bool) {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer func() {
+				if value := recover(); value != nil {
+					stack := debug.Stack()
+					mu.Lock()
+					defer mu.Unlock()
+					if returned {
+						p.recordPanic(PanicRecord[T]{Task: data, Value: value, Stack: string(stack)}, stack)
+						return
+					}
+					outcomes <- hedgeOutcome[R]{hedge: hedge, panic: &hedgePanic{value: value, stack: stack}}
+				}
+			}()
+			value, latency, err := call(ctx)
+			outcomes <- hedgeOutcome[R]{value: value, err: err, hedge: hedge, latency: latency}
+		}()
+	}
+
+	launch(false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	running := 1
+	for {
+		select {
+		case <-timer.C:
+			if h.fire() {
+				launch(true)
+				running++
+			}
+		case outcome := <-outcomes:
+			running--
+			if outcome.panic != nil {
+				panic(*outcome.panic)
+			}
+			if outcome.err != nil && running > 0 {
+				// the other copy may still succeed
+				continue
+			}
+
+			switch {
+			case outcome.hedge && outcome.err == nil:
+				// the first copy would have taken at least 'took', which is recorded as its latency
+				took := time.Since(started)
+				h.won(took)
+				h.observe(took)
+			case outcome.err == nil:
+				h.observe(outcome.latency)
+			}
+			return outcome.value, outcome.err
+		}
+	}
+}
+
+// 'HedgeStats' returns the hedging counts (zero without 'WithHedging')
+func (p *Pool[T, R]) HedgeStats() HedgeStats {
+	if p.hedge == nil {
+		return HedgeStats{}
+	}
+	return p.hedge.snapshot()
+}
+
+// 'WithHedging' sends a second copy of a call which is slower than the hedge delay (see 'HedgePolicy')
+func WithHedging(policy HedgePolicy) Option {
+	return func(c *config) {
+		c.hedge = &policy
+	}
+}
@@ -0,0 +1,199 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeDelay(t *testing.T) {
+	// 'samples' latencies of 1ms, 2ms, ... are observed before the delay is asked for
+	tests := []struct {
+		name    string
+		policy  HedgePolicy
+		samples int
+		want    time.Duration
+		wantOK  bool
+	}{
+		{"a fixed delay from the first call on", HedgePolicy{Delay: 150 * time.Millisecond}, 0, 150 * time.Millisecond, true},
+		{"a fixed delay ignores the latencies", HedgePolicy{Delay: 150 * time.Millisecond}, 100, 150 * time.Millisecond, true},
+		{"no percentile before 'MinSamples' calls", HedgePolicy{Percentile: 0.95}, 99, 0, false},
+		{"the 95th percentile", HedgePolicy{Percentile: 0.95}, 100, 95 * time.Millisecond, true},
+		{"the median, computed again every 50 calls", HedgePolicy{Percentile: 0.5, MinSamples: 10}, 60, 30 * time.Millisecond, true},
+		{"at least 'MinDelay'", HedgePolicy{Percentile: 0.95, MinDelay: 120 * time.Millisecond}, 100, 120 * time.Millisecond, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newHedger(test.policy)
+			for i := 1; i <= test.samples; i++ {
+				h.observe(time.Duration(i) * time.Millisecond)
+			}
+			delay, ok := h.start()
+			if delay != test.want || ok != test.wantOK {
+				t.Errorf("got delay %v (%v), want %v (%v)", delay, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestHedgeCap(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRatio   float64
+		calls      int
+		fires      int
+		wantHedged int
+	}{
+		{"10% of the calls", 0.1, 100, 20, 10},
+		{"the default 5%", 0, 100, 20, 5},
+		{"never more than half", 0.9, 100, 100, 50},
+		{"no hedge for the first call", 0.5, 1, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newHedger(HedgePolicy{Delay: time.Millisecond, MaxRatio: test.maxRatio})
+			for i := 0; i < test.calls; i++ {
+				h.start()
+			}
+			for i := 0; i < test.fires; i++ {
+				h.fire()
+			}
+			stats := h.snapshot()
+			if stats.Hedged != test.wantHedged || stats.Denied != test.fires-test.wantHedged {
+				t.Errorf("got %v hedged and %v denied, want %v and %v", stats.Hedged, stats.Denied, test.wantHedged, test.fires-test.wantHedged)
+			}
+		})
+	}
+}
+
+// the wins are kept in a window of 'hedgeSamples', the older ones still count in 'Saved'
+func TestHedgeSavedWindow(t *testing.T) {
+	h := newHedger(HedgePolicy{Percentile: 0.95})
+	for i := 0; i < hedgeSamples; i++ {
+		h.observe(time.Second)
+	}
+	for i := 0; i < 2500; i++ {
+		h.won(100 * time.Millisecond)
+	}
+	if len(h.wins) != hedgeSamples {
+		t.Errorf("%v wins kept, want %v", len(h.wins), hedgeSamples)
+	}
+	if stats := h.snapshot(); stats.Won != 2500 || stats.Saved != 2500*900*time.Millisecond {
+		t.Errorf("got %v wins which saved %v, want 2500 and %v", stats.Won, stats.Saved, 2500*900*time.Millisecond)
+	}
+}
+
+// the first copy of task 0 never returns on its own, the hedge wins and the first copy is cancelled
+func TestHedgedCall(t *testing.T) {
+	var copies, cancelled atomic.Int64
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		if data == 0 && copies.Add(1) == 1 {
+			<-ctx.Done()
+			cancelled.Add(1)
+			return 0, ctx.Err()
+		}
+		return data, nil
+	}, WithHedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxRatio: 0.5}))
+
+	go func() {
+		defer p.Close()
+		for i := 9; i >= 0; i-- {
+			p.Submit(i)
+		}
+	}()
+	for result := range p.Results() {
+		if result.Status != Succeeded {
+			t.Errorf("task %v ended %v: %v", result.Task, result.Status, result.Err)
+		}
+		if result.Task == 0 && result.Latency > time.Second {
+			t.Errorf("the hedged task took %v", result.Latency)
+		}
+	}
+	p.Wait()
+
+	if stats := p.HedgeStats(); stats.Calls != 10 || stats.Hedged != 1 || stats.Won != 1 {
+		t.Errorf("got %v calls, %v hedged and %v won, want 10, 1 and 1", stats.Calls, stats.Hedged, stats.Won)
+	}
+	if cancelled.Load() != 1 {
+		t.Error("the first copy was not cancelled")
+	}
+}
+
+// the first copy of task 0 panics once it is cancelled, after the hedge won: the task succeeded and the panic is recorded
+func TestHedgedCallLoserPanics(t *testing.T) {
+	var copies, reported atomic.Int64
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		if data == 0 && copies.Add(1) == 1 {
+			<-ctx.Done()
+			var response map[string]int
+			response["id"] = data // assignment to entry in nil map
+		}
+		return data, nil
+	}, WithHedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxRatio: 0.5}), WithPanicPolicy(PanicPolicy{OnPanic: func(value any, stack []byte) {
+		reported.Add(1)
+	}}))
+
+	go func() {
+		defer p.Close()
+		for i := 9; i >= 0; i-- {
+			p.Submit(i)
+		}
+	}()
+	for result := range p.Results() {
+		if result.Status != Succeeded {
+			t.Errorf("task %v ended %v: %v", result.Task, result.Status, result.Err)
+		}
+	}
+	// 'Wait' returns once the losing copy returned, its panic is recorded by then
+	p.Wait()
+
+	report := p.Report()
+	if len(report.Panics) != 1 || reported.Load() != 1 {
+		t.Fatalf("%v panics recorded and %v reported, want 1", len(report.Panics), reported.Load())
+	}
+	if record := report.Panics[0]; record.Task != 0 || record.Stack == "" {
+		t.Errorf("got the record %+v", record)
+	}
+	if stats := p.HedgeStats(); stats.Won != 1 {
+		t.Errorf("got %v won, want 1", stats.Won)
+	}
+}
+
+// a hedge copy waits for a slot of the adaptive limiter as any other call, with a limit of 1 it never runs
+// next to the first copy, once the first copy returned it is cancelled (or runs too late to win)
+func TestHedgedCallLimited(t *testing.T) {
+	limiter := NewAdaptiveLimiter(LimitPolicy{InitialLimit: 1, MinLimit: 1, MaxLimit: 1})
+	var running, most atomic.Int64
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		if data == 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return data, nil
+	}, WithAdaptiveLimit(limiter), WithHedging(HedgePolicy{Delay: 5 * time.Millisecond, MaxRatio: 0.5}))
+
+	go func() {
+		defer p.Close()
+		for i := 3; i >= 0; i-- {
+			p.Submit(i)
+		}
+	}()
+	if n := drain(p); n != 4 {
+		t.Fatalf("got %v results, want 4", n)
+	}
+	p.Wait()
+
+	if most.Load() != 1 {
+		t.Errorf("%v copies ran at once with a limit of 1", most.Load())
+	}
+	if stats := p.HedgeStats(); stats.Hedged != 1 || stats.Won != 0 {
+		t.Errorf("got %v hedged and %v won, want 1 and 0", stats.Hedged, stats.Won)
+	}
+	if inFlight := limiter.InFlight(); inFlight != 0 {
+		t.Errorf("%v slots still in flight", inFlight)
+	}
+}
@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 'DefaultLatencyBuckets' are the upper bounds of the latency histogram buckets (same spacing as Prometheus defaults)
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// 'Histogram' counts durations into buckets, a duration goes into the first bucket whose bound it does not exceed
+// durations above the last bound go into an extra overflow bucket
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64
+	sum    time.Duration
+	count  uint64
+}
+
+// 'HistogramSnapshot' is a copy of a histogram at one moment
+// 'Counts' has one more entry than 'Bounds' (the overflow bucket), counts are per bucket (not cumulative)
+type HistogramSnapshot struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Sum    time.Duration
+	Count  uint64
+}
+
+// 'NewHistogram' uses 'bounds' (sorted ascending) or 'DefaultLatencyBuckets' when 'bounds' is empty
+func NewHistogram(bounds []time.Duration) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	return &Histogram{
+		bounds: append([]time.Duration(nil), bounds...),
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// 'Observe' adds one duration
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += d
+	h.count++
+}
+
+// 'Snapshot' copies the current counts
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: append([]uint64(nil), h.counts...),
+		Sum:    h.sum,
+		Count:  h.count,
+	}
+}
+
+// 'Mean' is the average observed duration
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// 'Quantile' estimates the 'q' quantile (0-1) as the upper bound of the bucket it falls in
+// the overflow bucket has no upper bound so it reports the last bound
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(s.Count))
+	if rank >= s.Count {
+		rank = s.Count - 1
+	}
+	var seen uint64
+	for i, count := range s.Counts {
+		seen += count
+		if seen > rank {
+			if i < len(s.Bounds) {
+				return s.Bounds[i]
+			}
+			break
+		}
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+// 'String' renders the non-empty buckets as text bars, one line per bucket
+func (s HistogramSnapshot) String() string {
+	var max uint64
+	for _, count := range s.Counts {
+		if count > max {
+			max = count
+		}
+	}
+	if max == 0 {
+		return "(no observations)\n"
+	}
+
+	var b strings.Builder
+	for i, count := range s.Counts {
+		if count == 0 {
+			continue
+		}
+		var label string
+		if i < len(s.Bounds) {
+			label = "<= " + s.Bounds[i].String()
+		} else {
+			label = "> " + s.Bounds[len(s.Bounds)-1].String()
+		}
+		bar := strings.Repeat("#", int(1+count*39/max))
+		fmt.Fprintf(&b, "%10v %-40v %v\n", label, bar, count)
+	}
+	return b.String()
+}
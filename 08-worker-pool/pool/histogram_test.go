@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	bounds := []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}
+
+	// a duration goes into the first bucket whose bound it does not exceed
+	tests := []struct {
+		duration   time.Duration
+		wantBucket int
+	}{
+		{0, 0},
+		{10 * time.Millisecond, 0},
+		{10*time.Millisecond + 1, 1},
+		{100 * time.Millisecond, 1},
+		{100*time.Millisecond + 1, 2},
+		{time.Hour, 2},
+	}
+	for _, test := range tests {
+		h := NewHistogram(bounds)
+		h.Observe(test.duration)
+		snapshot := h.Snapshot()
+		for i, count := range snapshot.Counts {
+			if (i == test.wantBucket) != (count == 1) {
+				t.Errorf("%v was counted in the buckets %v, want bucket %v", test.duration, snapshot.Counts, test.wantBucket)
+				break
+			}
+		}
+		if snapshot.Count != 1 || snapshot.Sum != test.duration {
+			t.Errorf("%v: got count %v and sum %v", test.duration, snapshot.Count, snapshot.Sum)
+		}
+	}
+
+	if h := NewHistogram(nil); len(h.Snapshot().Counts) != len(DefaultLatencyBuckets)+1 {
+		t.Errorf("got %v buckets without bounds, want the %v default ones and the overflow", len(h.Snapshot().Counts), len(DefaultLatencyBuckets))
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	// 5 durations <= 10ms, 3 <= 100ms, 2 above 100ms
+	snapshot := HistogramSnapshot{
+		Bounds: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond},
+		Counts: []uint64{5, 3, 2},
+		Sum:    time.Second,
+		Count:  10,
+	}
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{0.49, 10 * time.Millisecond},
+		{0.5, 100 * time.Millisecond},
+		{0.79, 100 * time.Millisecond},
+		// the overflow bucket reports the last bound
+		{0.8, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if got := snapshot.Quantile(test.q); got != test.want {
+			t.Errorf("the %v quantile is %v, want %v", test.q, got, test.want)
+		}
+	}
+	if mean := snapshot.Mean(); mean != 100*time.Millisecond {
+		t.Errorf("the mean is %v, want 100ms", mean)
+	}
+
+	var empty HistogramSnapshot
+	if empty.Quantile(0.5) != 0 || empty.Mean() != 0 || empty.String() != "(no observations)\n" {
+		t.Errorf("an empty histogram has the median %v, the mean %v and the text %q", empty.Quantile(0.5), empty.Mean(), empty.String())
+	}
+}
+
+func TestHistogramString(t *testing.T) {
+	snapshot := HistogramSnapshot{
+		Bounds: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond},
+		Counts: []uint64{4, 0, 2},
+		Count:  6,
+	}
+	// empty buckets are left out, the overflow bucket is labelled with the last bound
+	want := "   <= 10ms ######################################## 4\n" +
+		"   > 100ms ####################                     2\n"
+	if got := snapshot.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
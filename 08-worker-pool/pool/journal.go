@@ -0,0 +1,366 @@
+package pool
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the journal is an optional write-ahead log that lets a pool survive restarts
+// every submitted task is appended before it is queued and every finished task is appended once it is done
+// on restart 'OpenJournal' replays the file and 'Pool.Recover()' queues only the tasks that never finished
+// the file has one JSON record per line:
+//
+//	{"op":"submit","id":1,"task":{...}}
+//	{"op":"submit","id":2,"task":{...}}
+//	{"op":"done","id":1,"status":"succeeded"}
+//	{"op":"done","id":2,"status":"quarantined"}
+//
+// 'Succeeded', 'Failed' and 'Quarantined' tasks are finished (a quarantined task would only panic again),
+// 'Cancelled' and 'NotStarted' tasks run again after a restart
+// a half written last line (a crash mid write) is dropped, a line which cannot be read before it is an error
+
+// 'SyncPolicy' is when the journal calls 'fsync'
+type SyncPolicy int
+
+const (
+	// 'SyncAlways' fsyncs after every record (slowest, nothing acknowledged is lost)
+	SyncAlways SyncPolicy = iota
+	// 'SyncInterval' fsyncs every 'JournalOptions.SyncInterval' (a crash loses at most that much)
+	SyncInterval
+	// 'SyncNever' leaves flushing to the operating system (survives a process crash, not a machine crash)
+	SyncNever
+)
+
+// 'JournalOptions' configures 'OpenJournal'
+// 'CompactEvery' rewrites the file with only the unfinished tasks after that many finished tasks (0 = only on open)
+// 'Encode' and 'Decode' convert a task to and from JSON (default 'encoding/json')
+type JournalOptions[T any] struct {
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	CompactEvery int
+	Encode       func(T) ([]byte, error)
+	Decode       func([]byte) (T, error)
+}
+
+type journalRecord struct {
+	Op     string          `json:"op"`
+	ID     uint64          `json:"id"`
+	Task   json.RawMessage `json:"task,omitempty"`
+	Status string          `json:"status,omitempty"`
+}
+
+// 'Journal' is the on-disk task journal of a 'Pool[T, R]' (see 'WithJournal')
+// close the journal after the pool is done ('Wait') so every completion is written
+type Journal[T any] struct {
+	mu      sync.Mutex
+	path    string
+	options JournalOptions[T]
+	file    *os.File
+	nextID  uint64
+	pending map[uint64]json.RawMessage
+	done    int
+	dirty   bool
+	stop    chan struct{}
+	stopped chan struct{}
+	closed  bool
+}
+
+// 'journaledTask' is a task recovered from the journal with its original id
+type journaledTask struct {
+	id   uint64
+	data any
+}
+
+// 'taskJournal' is what the pool needs from a 'Journal[T]' (the pool config is not generic)
+type taskJournal interface {
+	submitted(data any) (uint64, error)
+	completed(id uint64, status Status) error
+	recovered() ([]journaledTask, error)
+}
+
+// 'OpenJournal' opens (or creates) the journal at 'path', replays it and compacts it
+func OpenJournal[T any](path string, options JournalOptions[T]) (*Journal[T], error) {
+	if options.Encode == nil {
+		options.Encode = func(data T) ([]byte, error) { return json.Marshal(data) }
+	}
+	if options.Decode == nil {
+		options.Decode = func(raw []byte) (T, error) {
+			var data T
+			err := json.Unmarshal(raw, &data)
+			return data, err
+		}
+	}
+	if options.Sync == SyncInterval && options.SyncInterval <= 0 {
+		options.SyncInterval = time.Second
+	}
+
+	j := &Journal[T]{
+		path:    path,
+		options: options,
+		nextID:  1,
+		pending: make(map[uint64]json.RawMessage),
+	}
+
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+
+	if options.Sync == SyncInterval {
+		j.stop = make(chan struct{})
+		j.stopped = make(chan struct{})
+		go j.syncLoop()
+	}
+	return j, nil
+}
+
+// 'replay' reads every record, a half written last line (crash mid write) is ignored
+// any other line which cannot be read is corrupt, the journal is not opened rather than tasks silently lost
+func (j *Journal[T]) replay() error {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	// 'torn' is the error of the previous line, fine only if no line follows it
+	var torn error
+	for line := 1; scanner.Scan(); line++ {
+		if torn != nil {
+			return torn
+		}
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			torn = fmt.Errorf("pool: journal %v line %v is corrupt: %w", j.path, line, err)
+			continue
+		}
+		switch record.Op {
+		case "submit":
+			j.pending[record.ID] = record.Task
+		case "done":
+			delete(j.pending, record.ID)
+		}
+		if record.ID >= j.nextID {
+			j.nextID = record.ID + 1
+		}
+	}
+	return scanner.Err()
+}
+
+// 'compact' rewrites the journal with only the unfinished tasks (temp file, fsync, rename, fsync of the directory)
+// the rename is only durable once the directory is synced, a crash before would bring back the old journal
+// the caller holds 'j.mu' or no other goroutine uses the journal yet
+func (j *Journal[T]) compact() error {
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, id := range j.pendingIDs() {
+		if err := encoder.Encode(journalRecord{Op: "submit", ID: id, Task: j.pending[id]}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(j.path)); err != nil {
+		return err
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	j.done = 0
+	return err
+}
+
+// 'syncDir' fsyncs a directory so the entries renamed in it survive a machine crash
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := dir.Sync(); err != nil {
+		dir.Close()
+		return err
+	}
+	return dir.Close()
+}
+
+func (j *Journal[T]) pendingIDs() []uint64 {
+	ids := make([]uint64, 0, len(j.pending))
+	for id := range j.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	return ids
+}
+
+// 'append' writes one record and fsyncs it according to the sync policy, the caller holds 'j.mu'
+func (j *Journal[T]) append(record journalRecord) error {
+	if j.closed {
+		return errors.New("pool: journal closed")
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	switch j.options.Sync {
+	case SyncAlways:
+		return j.file.Sync()
+	case SyncInterval:
+		j.dirty = true
+	}
+	return nil
+}
+
+func (j *Journal[T]) submitted(data any) (uint64, error) {
+	task, ok := data.(T)
+	if !ok {
+		return 0, fmt.Errorf("pool: journal of %T got a %T task", *new(T), data)
+	}
+	raw, err := j.options.Encode(task)
+	if err != nil {
+		return 0, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	id := j.nextID
+	if err := j.append(journalRecord{Op: "submit", ID: id, Task: raw}); err != nil {
+		return 0, err
+	}
+	j.nextID++
+	j.pending[id] = raw
+	return id, nil
+}
+
+func (j *Journal[T]) completed(id uint64, status Status) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.append(journalRecord{Op: "done", ID: id, Status: status.String()}); err != nil {
+		return err
+	}
+	delete(j.pending, id)
+	j.done++
+
+	if j.options.CompactEvery > 0 && j.done >= j.options.CompactEvery {
+		return j.compact()
+	}
+	return nil
+}
+
+func (j *Journal[T]) recovered() ([]journaledTask, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var tasks []journaledTask
+	for _, id := range j.pendingIDs() {
+		data, err := j.options.Decode(j.pending[id])
+		if err != nil {
+			return nil, fmt.Errorf("pool: journal task %v: %w", id, err)
+		}
+		tasks = append(tasks, journaledTask{id: id, data: data})
+	}
+	return tasks, nil
+}
+
+// 'Add' appends a task to the journal without running it, 'Pool.Recover()' queues it later
+// it turns the journal into a durable queue: add every task first, then let the pool recover them
+func (j *Journal[T]) Add(task T) error {
+	_, err := j.submitted(task)
+	return err
+}
+
+// 'Pending' is the number of submitted tasks that have not finished
+func (j *Journal[T]) Pending() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending)
+}
+
+// 'syncLoop' fsyncs the file every 'SyncInterval' when records were written
+func (j *Journal[T]) syncLoop() {
+	defer close(j.stopped)
+	ticker := time.NewTicker(j.options.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			if j.dirty && !j.closed {
+				j.file.Sync()
+				j.dirty = false
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// 'Close' fsyncs and closes the journal file, it is safe to call 'Close' more than once
+func (j *Journal[T]) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	j.mu.Unlock()
+
+	if j.stop != nil {
+		close(j.stop)
+		<-j.stopped
+	}
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// 'WithJournal' appends every submitted and finished task to 'journal'
+// 'T' must be the task type of the pool, 'Submit' returns an error for a task the journal cannot write
+func WithJournal[T any](journal *Journal[T]) Option {
+	return func(c *config) {
+		c.journal = journal
+	}
+}
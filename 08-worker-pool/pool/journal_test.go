@@ -0,0 +1,194 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       string
+		wantPending int
+		wantErr     bool
+	}{
+		{"no journal yet", "", 0, false},
+		{
+			"the finished tasks are dropped",
+			`{"op":"submit","id":1,"task":1}` + "\n" +
+				`{"op":"submit","id":2,"task":2}` + "\n" +
+				`{"op":"submit","id":3,"task":3}` + "\n" +
+				`{"op":"done","id":1,"status":"succeeded"}` + "\n" +
+				`{"op":"done","id":3,"status":"quarantined"}` + "\n",
+			1, false,
+		},
+		{
+			"a half written last line is ignored",
+			`{"op":"submit","id":1,"task":1}` + "\n" +
+				`{"op":"submit","id":2,"task":2}` + "\n" +
+				`{"op":"done","id":1,"sta`,
+			2, false,
+		},
+		{
+			"a corrupt line before the last one is an error",
+			`{"op":"submit","id":1,"task":1}` + "\n" +
+				`{"op":"sub` + "\n" +
+				`{"op":"submit","id":3,"task":3}` + "\n",
+			0, true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks.journal")
+			if test.lines != "" {
+				if err := os.WriteFile(path, []byte(test.lines), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			journal, err := OpenJournal[int](path, JournalOptions[int]{})
+			if test.wantErr {
+				if err == nil {
+					journal.Close()
+					t.Fatal("opened a corrupt journal")
+				}
+				// the corrupt journal is left as it was for a look at it
+				if raw, _ := os.ReadFile(path); string(raw) != test.lines {
+					t.Errorf("the corrupt journal was rewritten to %q", raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer journal.Close()
+			if pending := journal.Pending(); pending != test.wantPending {
+				t.Errorf("%v tasks pending, want %v", pending, test.wantPending)
+			}
+		})
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.journal")
+	journal, err := OpenJournal[int](path, JournalOptions[int]{CompactEvery: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := journal.Add(i * 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := uint64(1); id <= 3; id++ {
+		if err := journal.completed(id, Succeeded); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the third finished task compacted the file to the 2 unfinished ones
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"op":"submit","id":4,"task":40}` + "\n" + `{"op":"submit","id":5,"task":50}` + "\n"
+	if string(raw) != want {
+		t.Errorf("the compacted journal is %q, want %q", raw, want)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reopened journal recovers them with their ids and goes on after the last id
+	journal, err = OpenJournal[int](path, JournalOptions[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	tasks, err := journal.recovered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].id != 4 || tasks[0].data != 40 || tasks[1].id != 5 || tasks[1].data != 50 {
+		t.Errorf("recovered %v, want tasks 4 and 5", tasks)
+	}
+	if id, err := journal.submitted(60); err != nil || id != 6 {
+		t.Errorf("the next task got id %v (%v), want 6", id, err)
+	}
+}
+
+// a journal with a half written last line is opened and compacted without it
+func TestJournalCompactTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.journal")
+	torn := `{"op":"submit","id":1,"task":1}` + "\n" + `{"op":"submit","id":2,"ta`
+	if err := os.WriteFile(path, []byte(torn), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	journal, err := OpenJournal[int](path, JournalOptions[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Add(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the torn task was never acknowledged, its id goes to the new task
+	want := `{"op":"submit","id":1,"task":1}` + "\n" + `{"op":"submit","id":2,"task":3}` + "\n"
+	if string(raw) != want {
+		t.Errorf("the journal is %q, want %q", raw, want)
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the temp file of the compaction is left: %v", err)
+	}
+}
+
+// a pool recovers the unfinished tasks, once they ran a reopened journal has none left
+func TestJournalRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.journal")
+	journal, err := OpenJournal[int](path, JournalOptions[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := journal.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := New(2, func(ctx context.Context, data int) (int, error) {
+		return data, nil
+	}, WithJournal(journal))
+	queued, err := p.Recover()
+	if err != nil || queued != 3 {
+		t.Fatalf("recovered %v tasks (%v), want 3", queued, err)
+	}
+	p.Close()
+	if n := drain(p); n != 3 {
+		t.Errorf("got %v results, want 3", n)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, err = OpenJournal[int](path, JournalOptions[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if pending := journal.Pending(); pending != 0 {
+		t.Errorf("%v tasks pending after the run, want 0", pending)
+	}
+}
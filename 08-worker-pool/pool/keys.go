@@ -0,0 +1,182 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// tasks sharing a key (the same account id for example) must not run at the same time or out of order
+// 'SubmitKey' queues at most one task per key in the pool, the tasks behind it wait in the key's queue
+// the worker finishing a keyed task runs the next task of the same key right away, so:
+// -tasks with the same key run one at a time, in the order they were submitted
+// -tasks with different keys (and tasks without a key) keep running in parallel
+
+// tasks of a key are already in order, 'WithOrderedResults' can not be combined with keys
+// (a waiting task would hold a reorder window slot the task in front of it needs to finish)
+// after a restart the journal ('WithJournal') recovers keyed tasks without their key
+
+// 'ErrKeyedOrdered' is returned by 'SubmitKey' on a pool with 'WithOrderedResults'
+var ErrKeyedOrdered = errors.New("pool: keyed tasks can not be combined with ordered results")
+
+// 'keyQueue' is the state of one key
+// 'active' is true while a task of the key is queued in the pool or running, 'waiting' are the tasks behind it
+// 'refs' counts the 'SubmitKey' calls using the queue, it is removed from the map once it is idle and unused
+// 'tickets' and 'serving' make the 'SubmitKey' calls of the key take turns, 'turn' is signalled after each
+type keyQueue[T any] struct {
+	mu      sync.Mutex
+	active  bool
+	waiting []job[T]
+	refs    int
+	tickets uint64
+	serving uint64
+	turn    *sync.Cond
+}
+
+// 'keyQueues' are the queues of the keys with a task in the pool
+// lock order: 'mu' before a 'keyQueue.mu', never the other way around
+type keyQueues[T any] struct {
+	mu     sync.Mutex
+	queues map[string]*keyQueue[T]
+}
+
+// 'SubmitKey' writes a task with a key to the 'Normal' priority lane, see 'SubmitKeyPriority'
+func (p *Pool[T, R]) SubmitKey(key string, data T) error {
+	return p.SubmitKeyPriority(key, data, Normal)
+}
+
+// 'SubmitKeyPriority' writes a task which runs only after every earlier task with the same 'key' has finished
+// an empty key is no key ('SubmitPriority')
+// while a task of 'key' is in the pool the new task waits in the key's queue and 'SubmitKey' returns right away
+func (p *Pool[T, R]) SubmitKeyPriority(key string, data T, priority Priority) error {
+	if key == "" {
+		return p.SubmitPriority(data, priority)
+	}
+	if p.ordered != nil {
+		return ErrKeyedOrdered
+	}
+	if p.batch != nil {
+		return ErrKeyedBatch
+	}
+
+	q := p.keys.acquire(key)
+	defer p.keys.releaseRef(key, q)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// the submissions of a key take turns in arrival order
+	ticket := q.tickets
+	q.tickets++
+	for ticket != q.serving {
+		q.turn.Wait()
+	}
+	defer func() {
+		q.serving++
+		q.turn.Broadcast()
+	}()
+
+	if p.ctx.Err() != nil {
+		return ErrStopped
+	}
+	select {
+	case <-p.closing:
+		return ErrStopped
+	default:
+	}
+
+	j := job[T]{data: data, priority: priority, key: key}
+	if q.active {
+		if err := p.journalSubmitted(&j); err != nil {
+			return err
+		}
+		j.submitted = time.Now()
+		q.waiting = append(q.waiting, j)
+		p.submitted.Add(1)
+		return nil
+	}
+
+	// no task of the key is in the pool: queue this one
+	// 'q.mu' is unlocked meanwhile because queueing blocks while the lanes are full (the next submission waits its turn)
+	// 'active' is set first, the task may already finish (and clear it) before 'submitJob' returns
+	q.active = true
+	q.mu.Unlock()
+	err := p.submitJob(j)
+	q.mu.Lock()
+	if err != nil {
+		q.active = false
+		return err
+	}
+	return nil
+}
+
+// 'acquire' returns the queue of 'key' and counts the caller as a user
+func (k *keyQueues[T]) acquire(key string) *keyQueue[T] {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.queues == nil {
+		k.queues = make(map[string]*keyQueue[T])
+	}
+	q := k.queues[key]
+	if q == nil {
+		q = &keyQueue[T]{}
+		q.turn = sync.NewCond(&q.mu)
+		k.queues[key] = q
+	}
+	q.refs++
+	return q
+}
+
+// 'releaseRef' is the end of 'acquire', an idle queue without users is removed
+func (k *keyQueues[T]) releaseRef(key string, q *keyQueue[T]) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.refs--
+	k.removeIdle(key, q)
+}
+
+// 'next' is called by the worker that finished a task of 'key', it returns the next task of the key (nil when none)
+func (k *keyQueues[T]) next(key string) *job[T] {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q := k.queues[key]
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiting) == 0 {
+		q.active = false
+		k.removeIdle(key, q)
+		return nil
+	}
+	j := q.waiting[0]
+	q.waiting[0] = job[T]{}
+	q.waiting = q.waiting[1:]
+	return &j
+}
+
+// 'removeIdle' removes the queue of 'key' when it has no task and no user (called with both locks held)
+func (k *keyQueues[T]) removeIdle(key string, q *keyQueue[T]) {
+	if q.refs == 0 && !q.active && len(q.waiting) == 0 {
+		delete(k.queues, key)
+	}
+}
+
+// 'depth' is the number of tasks waiting behind another task of their key
+func (k *keyQueues[T]) depth() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	depth := 0
+	for _, q := range k.queues {
+		q.mu.Lock()
+		depth += len(q.waiting)
+		q.mu.Unlock()
+	}
+	return depth
+}
@@ -0,0 +1,265 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// go test -race ./08-worker-pool/pool
+
+// 'keyedTask' is task 'seq' of 'key'
+type keyedTask struct {
+	key string
+	seq int
+}
+
+// 'keyChecker' records the order each key's tasks ran in and how many ran at the same time
+type keyChecker struct {
+	mu      sync.Mutex
+	running map[string]int
+	order   map[string][]int
+	overlap atomic.Int64
+}
+
+func newKeyChecker() *keyChecker {
+	return &keyChecker{running: make(map[string]int), order: make(map[string][]int)}
+}
+
+// 'task' sleeps up to 'maxLatency' while marked running (tasks without a key are not recorded)
+func (c *keyChecker) task(maxLatency time.Duration) Task[keyedTask, int] {
+	return func(ctx context.Context, t keyedTask) (int, error) {
+		if t.key == "" {
+			return t.seq, nil
+		}
+
+		c.mu.Lock()
+		c.running[t.key]++
+		if c.running[t.key] > 1 {
+			c.overlap.Add(1)
+		}
+		c.order[t.key] = append(c.order[t.key], t.seq)
+		c.mu.Unlock()
+
+		if maxLatency > 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(maxLatency))))
+		}
+
+		c.mu.Lock()
+		c.running[t.key]--
+		c.mu.Unlock()
+		return t.seq, nil
+	}
+}
+
+// 'check' fails unless every key ran 'perKey' tasks in order and never two at a time
+func (c *keyChecker) check(t *testing.T, keys, perKey int) {
+	t.Helper()
+	if overlap := c.overlap.Load(); overlap > 0 {
+		t.Errorf("tasks of the same key ran at the same time %v times", overlap)
+	}
+	if len(c.order) != keys {
+		t.Fatalf("got tasks of %v keys, want %v", len(c.order), keys)
+	}
+	for key, order := range c.order {
+		if len(order) != perKey {
+			t.Errorf("key %v: ran %v tasks, want %v", key, len(order), perKey)
+		}
+		for i, seq := range order {
+			if seq != i {
+				t.Errorf("key %v: task %v ran as number %v (order %v)", key, seq, i, order)
+				break
+			}
+		}
+	}
+}
+
+// 'drain' reads every result and returns how many there were
+func drain[T, R any](p *Pool[T, R]) int {
+	n := 0
+	for range p.Results() {
+		n++
+	}
+	return n
+}
+
+func TestSubmitKeyOrder(t *testing.T) {
+	const keys, perKey = 20, 200
+
+	for _, scheduler := range []Scheduler{ChannelScheduler, WorkStealing} {
+		t.Run(scheduler.String(), func(t *testing.T) {
+			checker := newKeyChecker()
+			p := New(32, checker.task(200*time.Microsecond), WithScheduler(scheduler))
+
+			// the keys are interleaved and a share of unkeyed tasks is mixed in
+			go func() {
+				defer p.Close()
+				for seq := 0; seq < perKey; seq++ {
+					for k := 0; k < keys; k++ {
+						if err := p.SubmitKeyPriority(fmt.Sprint("account-", k), keyedTask{key: fmt.Sprint("account-", k), seq: seq}, Priority(rand.Intn(3))); err != nil {
+							t.Error(err)
+							return
+						}
+						if err := p.Submit(keyedTask{key: "", seq: seq}); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}
+			}()
+
+			if n := drain(p); n != 2*keys*perKey {
+				t.Fatalf("got %v results, want %v", n, 2*keys*perKey)
+			}
+			if err := p.Wait(); err != nil {
+				t.Fatal(err)
+			}
+
+			checker.check(t, keys, perKey)
+		})
+	}
+}
+
+func TestSubmitKeyConcurrentSubmitters(t *testing.T) {
+	const keys, perKey = 50, 100
+
+	checker := newKeyChecker()
+	p := New(16, checker.task(100*time.Microsecond))
+
+	// one submitting goroutine per key, all of them at the same time
+	var submitters sync.WaitGroup
+	for k := 0; k < keys; k++ {
+		submitters.Add(1)
+		go func(key string) {
+			defer submitters.Done()
+			for seq := 0; seq < perKey; seq++ {
+				if err := p.SubmitKey(key, keyedTask{key: key, seq: seq}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(fmt.Sprint("account-", k))
+	}
+	go func() {
+		submitters.Wait()
+		p.Close()
+	}()
+
+	if n := drain(p); n != keys*perKey {
+		t.Fatalf("got %v results, want %v", n, keys*perKey)
+	}
+	checker.check(t, keys, perKey)
+}
+
+func TestSubmitKeyParallelAcrossKeys(t *testing.T) {
+	const keys, perKey, latency = 8, 5, 20 * time.Millisecond
+
+	p := New(keys, func(ctx context.Context, t keyedTask) (int, error) {
+		time.Sleep(latency)
+		return t.seq, nil
+	})
+
+	startTime := time.Now()
+	go func() {
+		defer p.Close()
+		for seq := 0; seq < perKey; seq++ {
+			for k := 0; k < keys; k++ {
+				p.SubmitKey(fmt.Sprint(k), keyedTask{key: fmt.Sprint(k), seq: seq})
+			}
+		}
+	}()
+	drain(p)
+	elapsed := time.Since(startTime)
+
+	// one key after another would take keys*perKey*latency (800ms), in parallel it is about perKey*latency (100ms)
+	if elapsed > 2*perKey*latency {
+		t.Errorf("took %v, the keys did not run in parallel (want under %v)", elapsed, 2*perKey*latency)
+	}
+}
+
+func TestSubmitKeyPanicKeepsOrder(t *testing.T) {
+	const perKey = 50
+
+	var mu sync.Mutex
+	var order []int
+	p := New(4, func(ctx context.Context, t keyedTask) (int, error) {
+		mu.Lock()
+		order = append(order, t.seq)
+		mu.Unlock()
+		if t.seq%10 == 5 {
+			panic(fmt.Sprint("task ", t.seq))
+		}
+		return t.seq, nil
+	}, WithPanicPolicy(PanicPolicy{MaxPanics: 2}))
+
+	go func() {
+		defer p.Close()
+		for seq := 0; seq < perKey; seq++ {
+			p.SubmitKey("account", keyedTask{key: "account", seq: seq})
+		}
+	}()
+
+	statuses := make(map[Status]int)
+	for result := range p.Results() {
+		statuses[result.Status]++
+	}
+
+	// a panicking task runs twice in a row, then the key goes on with the next task
+	var want []int
+	for seq := 0; seq < perKey; seq++ {
+		want = append(want, seq)
+		if seq%10 == 5 {
+			want = append(want, seq)
+		}
+	}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("ran %v, want %v", order, want)
+	}
+	if statuses[Quarantined] != perKey/10 || statuses[Succeeded] != perKey-perKey/10 {
+		t.Errorf("statuses %v", statuses)
+	}
+}
+
+func TestSubmitKeyStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 10)
+	p := NewContext(ctx, 2, func(ctx context.Context, t keyedTask) (int, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	for seq := 0; seq < 10; seq++ {
+		if err := p.SubmitKey("account", keyedTask{key: "account", seq: seq}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	cancel()
+	if err := p.SubmitKey("account", keyedTask{key: "account", seq: 10}); err != ErrStopped {
+		t.Errorf("got %v, want ErrStopped", err)
+	}
+	p.Close()
+
+	// the first task is cancelled, the 9 waiting behind it never start, but every one is reported
+	statuses := make(map[Status]int)
+	for result := range p.Results() {
+		statuses[result.Status]++
+	}
+	if statuses[Cancelled] != 1 || statuses[NotStarted] != 9 {
+		t.Errorf("statuses %v, want 1 cancelled and 9 not started", statuses)
+	}
+}
+
+func TestSubmitKeyOrderedResults(t *testing.T) {
+	p := New(2, func(ctx context.Context, t keyedTask) (int, error) { return t.seq, nil }, WithOrderedResults(4))
+	defer p.Close()
+
+	if err := p.SubmitKey("account", keyedTask{}); err != ErrKeyedOrdered {
+		t.Errorf("got %v, want ErrKeyedOrdered", err)
+	}
+}
@@ -0,0 +1,103 @@
+package pool
+
+// 'Priority' picks the lane a task is queued in
+type Priority int
+
+const (
+	High Priority = iota
+	Normal
+	Low
+
+	numPriorities = 3
+)
+
+func (p Priority) String() string {
+	switch p {
+	case High:
+		return "high"
+	case Normal:
+		return "normal"
+	case Low:
+		return "low"
+	}
+	return "unknown"
+}
+
+// 'lane' maps any out of range priority to 'Normal'
+func (p Priority) lane() int {
+	if p < High || p > Low {
+		return int(Normal)
+	}
+	return int(p)
+}
+
+// default lane weights: out of every 10 tasks dispatched while all lanes are full, 6 are 'High', 3 'Normal' and 1 'Low'
+var defaultLaneWeights = [numPriorities]int{6, 3, 1}
+
+// 'dispatch' moves tasks from the lanes to the workers with weighted round robin
+// each lane gets 'weight' credits per round, a task from a lane spends one credit
+// the highest lane with credit and a queued task goes first, so higher lanes are preferred
+// but a lower lane is never starved: once the higher lanes spent their credits the lower lane is served
+// 'bufferedChannel' is unbuffered when lanes are used so the choice is made only when a worker is ready
+func (p *Pool[T, R]) dispatch() {
+	defer close(p.bufferedChannel)
+
+	weights := p.config.laneWeights
+	credits := weights
+
+	// a closed and drained lane is set to nil, a nil channel is never selected
+	lanes := p.lanes
+	open := numPriorities
+
+	for open > 0 {
+		j, ok := p.nextJob(&lanes, &open, &credits, weights)
+		if !ok {
+			continue
+		}
+		p.bufferedChannel <- j
+	}
+}
+
+// 'nextJob' returns the next task by lane credit, blocking until any lane has a task (or is closed)
+func (p *Pool[T, R]) nextJob(lanes *[numPriorities]chan job[T], open *int, credits *[numPriorities]int, weights [numPriorities]int) (job[T], bool) {
+	for round := 0; round < 2; round++ {
+		for i := range lanes {
+			if lanes[i] == nil || credits[i] <= 0 {
+				continue
+			}
+			select {
+			case j, ok := <-lanes[i]:
+				if !ok {
+					lanes[i] = nil
+					*open--
+					return j, false
+				}
+				credits[i]--
+				return j, true
+			default:
+			}
+		}
+		// every lane with a queued task is out of credit, start a new round
+		*credits = weights
+	}
+
+	// every lane is empty, wait for the next task of any lane
+	var j job[T]
+	var ok bool
+	var i int
+	select {
+	case j, ok = <-lanes[High]:
+		i = int(High)
+	case j, ok = <-lanes[Normal]:
+		i = int(Normal)
+	case j, ok = <-lanes[Low]:
+		i = int(Low)
+	}
+	if !ok {
+		lanes[i] = nil
+		*open--
+		return j, false
+	}
+	credits[i]--
+	return j, true
+}
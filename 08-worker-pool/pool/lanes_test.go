@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLaneWeights(t *testing.T) {
+	// 'queued' is the number of tasks in the high, normal and low lane before the dispatcher starts,
+	// 'want' the lanes they are dispatched from
+	tests := []struct {
+		name    string
+		weights [numPriorities]int
+		queued  [numPriorities]int
+		want    string
+	}{
+		{"6 high, 3 normal and 1 low per round", defaultLaneWeights, [numPriorities]int{10, 10, 10}, "HHHHHHNNNL HHHHNNNL NNNL NLLLLLLL"},
+		{"equal weights take turns", [numPriorities]int{1, 1, 1}, [numPriorities]int{3, 3, 3}, "HNL HNL HNL"},
+		{"a lone lane is not held back by its weight", defaultLaneWeights, [numPriorities]int{0, 0, 5}, "LLLLL"},
+		{"the low lane is served once the others are out of credit", [numPriorities]int{2, 1, 1}, [numPriorities]int{6, 0, 2}, "HHL HHL HH"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lanes [numPriorities]chan job[int]
+			for i := range lanes {
+				lanes[i] = make(chan job[int], test.queued[i])
+				for n := 0; n < test.queued[i]; n++ {
+					lanes[i] <- job[int]{priority: Priority(i)}
+				}
+				close(lanes[i])
+			}
+
+			p := &Pool[int, int]{}
+			credits := test.weights
+			open := numPriorities
+			var got strings.Builder
+			for open > 0 {
+				j, ok := p.nextJob(&lanes, &open, &credits, test.weights)
+				if ok {
+					got.WriteString(strings.ToUpper(j.priority.String()[:1]))
+				}
+			}
+			if want := strings.ReplaceAll(test.want, " ", ""); got.String() != want {
+				t.Errorf("dispatched %v, want %v", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestWithLaneWeights(t *testing.T) {
+	if weights := newConfig([]Option{WithLaneWeights(0, 5, -1)}).laneWeights; weights != [numPriorities]int{1, 5, 1} {
+		t.Errorf("got weights %v, want a weight of at least 1 per lane", weights)
+	}
+}
+
+// a task of an unknown priority is queued as 'Normal', every result keeps the priority it was submitted with
+func TestSubmitPriority(t *testing.T) {
+	p := New(2, func(ctx context.Context, data int) (int, error) { return data, nil })
+	go func() {
+		defer p.Close()
+		for i, priority := range []Priority{High, Normal, Low, Priority(7)} {
+			p.SubmitPriority(i, priority)
+		}
+	}()
+	for result := range p.Results() {
+		if want := []Priority{High, Normal, Low, Priority(7)}[result.Task]; result.Priority != want {
+			t.Errorf("task %v has priority %v, want %v", result.Task, result.Priority, want)
+		}
+	}
+	p.Wait()
+	if lane := Priority(7).lane(); lane != int(Normal) {
+		t.Errorf("an unknown priority goes to lane %v, want the normal lane", lane)
+	}
+}
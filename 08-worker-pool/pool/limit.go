@@ -0,0 +1,299 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// a fixed 'numberOfWorkers' is a guess: too few leaves the downstream idle, too many overloads it
+// (every call gets slower, then calls time out and fail)
+// an 'AdaptiveLimiter' caps the calls in flight and moves the cap from what it observes:
+// -'AIMD' (additive increase, multiplicative decrease, as TCP) grows the limit by 1 per round trip while calls succeed
+// and cuts it by 'Backoff' when a call fails or is slower than 'Timeout'
+// -'Gradient' compares the recent latency with the no-load latency, the limit shrinks once calls get slower
+// than 'Tolerance' times the no-load latency and grows by about sqrt(limit) per round trip otherwise
+// both start with a slow start: the limit grows by 1 per call (doubles per round trip) until the first decrease,
+// which halves the limit (the overload shows one round trip late, the limit before that was still fine)
+// give the pool more workers than the limit will need ('MaxLimit'), the workers above the limit wait for a slot
+
+// 'LimitAlgorithm' is how an 'AdaptiveLimiter' moves its limit
+type LimitAlgorithm int
+
+const (
+	// 'AIMD' grows the limit by 1 per round trip and cuts it on a failed or slow call (default)
+	AIMD LimitAlgorithm = iota
+	// 'Gradient' follows the ratio of the no-load latency to the recent latency
+	Gradient
+)
+
+func (a LimitAlgorithm) String() string {
+	switch a {
+	case AIMD:
+		return "aimd"
+	case Gradient:
+		return "gradient"
+	}
+	return "unknown"
+}
+
+// 'LimitPolicy' configures an 'AdaptiveLimiter', zero fields get the defaults in brackets
+// -'InitialLimit', 'MinLimit' and 'MaxLimit' bound the limit (10, 1, 1000)
+// -'Backoff' multiplies the limit on a failed call, or a call slower than 'Timeout' with 'AIMD' (0.9)
+// -'Timeout' is the latency 'AIMD' treats as overload (0 = only failures)
+// -'Tolerance' is how much slower than the no-load latency calls may get before 'Gradient' shrinks the limit (1.5)
+// -'IsFailure' classifies errors as overload (nil counts every error), 'context.Canceled' is never counted
+// and a call which panicked always is
+type LimitPolicy struct {
+	Algorithm    LimitAlgorithm
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+	Backoff      float64
+	Timeout      time.Duration
+	Tolerance    float64
+	IsFailure    func(err error) bool
+}
+
+// 'LimitSample' is the limit at one moment, with the calls in flight and the recent latency then
+type LimitSample struct {
+	Time     time.Time
+	Limit    int
+	InFlight int
+	Latency  time.Duration
+}
+
+// 'AdaptiveLimiter' is shared by every worker of a pool (or several pools calling the same downstream)
+type AdaptiveLimiter struct {
+	policy LimitPolicy
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	changed  chan struct{}
+	waiting  int
+
+	// 'slowStart' lasts until the first decrease, 'decreased' is when it happened
+	// a call started before the last decrease does not decrease the limit again (one cut per round trip)
+	slowStart bool
+	decreased time.Time
+
+	// 'short' is the recent latency (a moving average), 'noLoad' the lowest one seen (in nanoseconds)
+	short  float64
+	noLoad float64
+
+	history []LimitSample
+}
+
+// 'NewAdaptiveLimiter' returns a limiter at 'InitialLimit'
+func NewAdaptiveLimiter(policy LimitPolicy) *AdaptiveLimiter {
+	if policy.MinLimit < 1 {
+		policy.MinLimit = 1
+	}
+	if policy.MaxLimit < policy.MinLimit {
+		policy.MaxLimit = max(1000, policy.MinLimit)
+	}
+	if policy.InitialLimit < 1 {
+		policy.InitialLimit = 10
+	}
+	policy.InitialLimit = min(max(policy.InitialLimit, policy.MinLimit), policy.MaxLimit)
+	if policy.Backoff <= 0 || policy.Backoff >= 1 {
+		policy.Backoff = 0.9
+	}
+	if policy.Tolerance < 1 {
+		policy.Tolerance = 1.5
+	}
+
+	l := &AdaptiveLimiter{
+		policy:    policy,
+		limit:     float64(policy.InitialLimit),
+		changed:   make(chan struct{}),
+		slowStart: true,
+	}
+	l.record(time.Now())
+	return l
+}
+
+// 'acquire' blocks until a call fits under the limit or 'ctx' is cancelled, it returns when the call started
+func (l *AdaptiveLimiter) acquire(ctx context.Context) (time.Time, error) {
+	l.mu.Lock()
+	for l.inFlight >= int(l.limit) {
+		changed := l.changed
+		l.waiting++
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+			return time.Time{}, ctx.Err()
+		}
+
+		l.mu.Lock()
+		l.waiting--
+	}
+	l.inFlight++
+	l.mu.Unlock()
+	return time.Now(), nil
+}
+
+// 'release' ends a call 'acquire' let through and moves the limit from its latency and error
+func (l *AdaptiveLimiter) release(started time.Time, err error) {
+	now := time.Now()
+	latency := now.Sub(started)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the limit only grows while it is used, a half idle pool says nothing about the downstream
+	used := l.inFlight >= int(l.limit)/2
+	l.inFlight--
+	before := int(l.limit)
+
+	cancelled := errors.Is(err, context.Canceled)
+	if !cancelled {
+		l.observe(latency)
+	}
+
+	switch {
+	case cancelled:
+		// the pool stopped, the call says nothing about the downstream
+	case errors.Is(err, errPanicked) || err != nil && (l.policy.IsFailure == nil || l.policy.IsFailure(err)):
+		l.decrease(started, now)
+	case l.policy.Algorithm == Gradient:
+		l.gradient(started, now, used)
+	case l.policy.Timeout > 0 && latency > l.policy.Timeout:
+		l.decrease(started, now)
+	case used:
+		if l.slowStart {
+			l.limit++
+		} else {
+			l.limit += 1 / l.limit
+		}
+	}
+	l.limit = math.Min(math.Max(l.limit, float64(l.policy.MinLimit)), float64(l.policy.MaxLimit))
+
+	if int(l.limit) != before {
+		l.record(now)
+	}
+	// a freed slot (or a higher limit) may let waiting calls through
+	if l.waiting > 0 {
+		close(l.changed)
+		l.changed = make(chan struct{})
+	}
+}
+
+// 'decrease' cuts the limit by 'Backoff' (by half to end the slow start), once per round trip
+func (l *AdaptiveLimiter) decrease(started, now time.Time) {
+	if started.Before(l.decreased) {
+		return
+	}
+	if l.slowStart {
+		l.limit /= 2
+		l.slowStart = false
+	} else {
+		l.limit *= l.policy.Backoff
+	}
+	l.decreased = now
+}
+
+// 'gradient' moves the limit toward 'limit * gradient + sqrt(limit)', by a fifth of the way per round trip
+// the gradient is 'Tolerance * noLoad / short' capped to [0.5, 1]: 1 while calls are not slower than tolerated
+// (and while every call took no measurable time, 'short' is 0 then)
+func (l *AdaptiveLimiter) gradient(started, now time.Time, used bool) {
+	gradient := 1.0
+	if l.short > 0 {
+		gradient = math.Max(0.5, math.Min(1, l.policy.Tolerance*l.noLoad/l.short))
+	}
+	if gradient < 1 {
+		if l.slowStart {
+			l.decrease(started, now)
+			return
+		}
+	} else if !used {
+		return
+	}
+
+	if l.slowStart {
+		l.limit++
+		return
+	}
+	target := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit += (target - l.limit) * 0.2 / l.limit
+}
+
+// 'observe' adds a latency to the recent average (about the last 10 calls) and the no-load latency
+// the no-load latency follows the recent average down right away and up by 0.001% per call,
+// so a downstream that became slower for good is the new normal after some 100000 calls
+func (l *AdaptiveLimiter) observe(latency time.Duration) {
+	rtt := float64(latency)
+	if l.noLoad == 0 {
+		l.short, l.noLoad = rtt, rtt
+	}
+	l.short += (rtt - l.short) * 0.1
+	l.noLoad = math.Min(l.short, l.noLoad*1.00001)
+}
+
+// 'record' appends the current limit to the history, called with 'mu' held
+func (l *AdaptiveLimiter) record(now time.Time) {
+	l.history = append(l.history, LimitSample{
+		Time:     now,
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		Latency:  time.Duration(l.short),
+	})
+}
+
+// 'Limit' is the current limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// 'InFlight' is the number of calls holding a slot
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// 'History' is every change of the limit, the first sample is the initial limit
+func (l *AdaptiveLimiter) History() []LimitSample {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LimitSample(nil), l.history...)
+}
+
+// 'WriteHistoryCSV' writes the history with the time in milliseconds since the first sample, ready to plot:
+//
+//	elapsed_ms,limit,in_flight,latency_ms
+//	0,10,0,0
+//	101.2,11,10,100.1
+func (l *AdaptiveLimiter) WriteHistoryCSV(w io.Writer) error {
+	history := l.History()
+	if _, err := fmt.Fprintln(w, "elapsed_ms,limit,in_flight,latency_ms"); err != nil {
+		return err
+	}
+	for _, sample := range history {
+		elapsed := sample.Time.Sub(history[0].Time)
+		_, err := fmt.Fprintf(w, "%.1f,%v,%v,%.1f\n", float64(elapsed)/float64(time.Millisecond), sample.Limit, sample.InFlight, float64(sample.Latency)/float64(time.Millisecond))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 'WithAdaptiveLimit' makes every task attempt (retries included) wait for a slot of 'limiter'
+func WithAdaptiveLimit(limiter *AdaptiveLimiter) Option {
+	return func(c *config) {
+		c.limiter = limiter
+	}
+}
@@ -0,0 +1,184 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// 'fill' takes 'n' slots and gives them back after calls of 'latency' which failed with 'err'
+// each call starts after the previous decrease, so every failure may cut the limit
+func fill(t *testing.T, l *AdaptiveLimiter, n int, latency time.Duration, err error) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, acquireErr := l.acquire(context.Background()); acquireErr != nil {
+			t.Fatal(acquireErr)
+		}
+	}
+	time.Sleep(time.Millisecond)
+	for i := 0; i < n; i++ {
+		l.release(time.Now().Add(-latency), err)
+	}
+}
+
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	errOverload := errors.New("overload")
+
+	tests := []struct {
+		name string
+		// 'steps' run one after another, 'want' is the limit after each
+		steps []func(t *testing.T, l *AdaptiveLimiter)
+		want  []int
+	}{
+		{
+			name: "slow start grows by 1 per call while half the limit is in flight",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 10, 100*time.Microsecond, nil) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 14, 100*time.Microsecond, nil) },
+			},
+			want: []int{14, 20},
+		},
+		{
+			name: "the first failure halves, the next ones multiply by backoff",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+			},
+			want: []int{5, 4, 4},
+		},
+		{
+			name: "after the slow start the limit grows by 1 per round trip",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 5, 100*time.Microsecond, nil) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 5, 100*time.Microsecond, nil) },
+			},
+			want: []int{5, 5, 6},
+		},
+		{
+			name: "a call slower than the timeout is an overload",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, time.Second, nil) },
+			},
+			want: []int{5},
+		},
+		{
+			name: "a cancelled call does not move the limit",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 10, 100*time.Microsecond, context.Canceled) },
+			},
+			want: []int{10},
+		},
+		{
+			name: "the limit stays within the bounds",
+			steps: []func(t *testing.T, l *AdaptiveLimiter){
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+				func(t *testing.T, l *AdaptiveLimiter) { fill(t, l, 1, 100*time.Microsecond, errOverload) },
+			},
+			want: []int{5, 4, 4, 3, 3, 3, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := NewAdaptiveLimiter(LimitPolicy{InitialLimit: 10, MinLimit: 3, MaxLimit: 20, Backoff: 0.9, Timeout: 100 * time.Millisecond})
+			for i, step := range test.steps {
+				step(t, l)
+				if got := l.Limit(); got != test.want[i] {
+					t.Fatalf("step %v: limit %v, want %v", i, got, test.want[i])
+				}
+			}
+			if inFlight := l.InFlight(); inFlight != 0 {
+				t.Errorf("%v calls still in flight", inFlight)
+			}
+		})
+	}
+}
+
+func TestAdaptiveLimiterGradient(t *testing.T) {
+	l := NewAdaptiveLimiter(LimitPolicy{Algorithm: Gradient, InitialLimit: 20, MaxLimit: 100, Tolerance: 1.5})
+
+	// calls at the no-load latency grow the limit (slow start)
+	fill(t, l, 20, time.Millisecond, nil)
+	grown := l.Limit()
+	if grown <= 20 {
+		t.Fatalf("limit %v after calls at the no-load latency, want more than 20", grown)
+	}
+
+	// calls 4x slower than the no-load latency end the slow start and shrink the limit
+	for i := 0; i < 20; i++ {
+		fill(t, l, 5, 4*time.Millisecond, nil)
+	}
+	shrunk := l.Limit()
+	if shrunk >= grown/2 {
+		t.Fatalf("limit %v after slow calls, want less than %v", shrunk, grown/2)
+	}
+
+	// back at the no-load latency the limit grows again once the short term latency came down
+	for i := 0; i < 40; i++ {
+		fill(t, l, l.Limit(), time.Millisecond, nil)
+	}
+	if recovered := l.Limit(); recovered <= shrunk {
+		t.Fatalf("limit %v after the latency recovered, want more than %v", recovered, shrunk)
+	}
+}
+
+// calls which took no measurable time must not turn the limit into NaN
+func TestAdaptiveLimiterGradientZeroLatency(t *testing.T) {
+	l := NewAdaptiveLimiter(LimitPolicy{Algorithm: Gradient, InitialLimit: 10})
+	l.slowStart = false
+	l.short, l.noLoad = 0, 0
+	now := time.Now()
+	l.gradient(now, now, true)
+	if math.IsNaN(l.limit) || l.Limit() < 10 {
+		t.Fatalf("limit %v after calls of 0s", l.limit)
+	}
+}
+
+// a panicking task gives its slot back, with a limit of 2 two panics used to leave no slot at all
+func TestAdaptiveLimitPanic(t *testing.T) {
+	limiter := NewAdaptiveLimiter(LimitPolicy{InitialLimit: 2, MinLimit: 2, MaxLimit: 2})
+	p := New(4, func(ctx context.Context, data int) (int, error) {
+		if data < 2 {
+			panic("boom")
+		}
+		return data, nil
+	}, WithAdaptiveLimit(limiter), WithPanicPolicy(PanicPolicy{MaxPanics: 1}))
+
+	go func() {
+		defer p.Close()
+		for i := 0; i < 10; i++ {
+			p.Submit(i)
+		}
+	}()
+
+	done := make(chan map[Status]int)
+	go func() {
+		counts := make(map[Status]int)
+		for result := range p.Results() {
+			counts[result.Status]++
+		}
+		done <- counts
+	}()
+
+	select {
+	case counts := <-done:
+		if counts[Quarantined] != 2 || counts[Succeeded] != 8 {
+			t.Errorf("got %v, want 2 quarantined and 8 succeeded", counts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the pool hung with %v of %v slots in flight", limiter.InFlight(), limiter.Limit())
+	}
+	p.Wait()
+	if inFlight := limiter.InFlight(); inFlight != 0 {
+		t.Errorf("%v slots still in flight", inFlight)
+	}
+}
@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// 'MetricsHandler' serves the pool metrics in the Prometheus text exposition format (stdlib only)
+// mount it on '/metrics' and scrape it while the pool runs:
+//
+//	http.Handle("/metrics", workers.MetricsHandler())
+//	go http.ListenAndServe(":2112", nil)
+//
+// -worker_pool_queue_depth                  tasks waiting in the lanes ('len(bufferedChannel)')
+// -worker_pool_workers{state}               busy and idle workers
+// -worker_pool_tasks_total{status}          tasks by 'Status' (succeeded, failed, cancelled, not_started, quarantined)
+// -worker_pool_task_panics_total            panics recovered from tasks
+// -worker_pool_task_retries_total           retries made by the retry policy
+// -worker_pool_task_latency_seconds         histogram of the time from 'Submit' to the result
+// -worker_pool_concurrency_limit            calls allowed in flight by the adaptive limiter (with 'WithAdaptiveLimit')
+// -worker_pool_circuit_breaker_state{state}  1 for the current breaker state (with 'WithCircuitBreaker')
+// -worker_pool_circuit_breaker_rejected_total calls refused by the breaker
+// -worker_pool_task_requeued_total          tasks put back by the breaker ('BreakerRequeue')
+func (p *Pool[T, R]) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		p.WriteMetrics(w)
+	})
+}
+
+// 'WriteMetrics' writes the metrics served by 'MetricsHandler'
+func (p *Pool[T, R]) WriteMetrics(w io.Writer) {
+	size := p.Size()
+	busy := p.Busy()
+
+	p.mu.Lock()
+	report := p.report
+	p.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP worker_pool_queue_depth Tasks waiting in the queue.")
+	fmt.Fprintln(w, "# TYPE worker_pool_queue_depth gauge")
+	fmt.Fprintf(w, "worker_pool_queue_depth %v\n", p.QueueDepth())
+
+	fmt.Fprintln(w, "# HELP worker_pool_workers Workers by state.")
+	fmt.Fprintln(w, "# TYPE worker_pool_workers gauge")
+	fmt.Fprintf(w, "worker_pool_workers{state=\"busy\"} %v\n", busy)
+	fmt.Fprintf(w, "worker_pool_workers{state=\"idle\"} %v\n", max(size-busy, 0))
+
+	fmt.Fprintln(w, "# HELP worker_pool_tasks_total Tasks processed by outcome.")
+	fmt.Fprintln(w, "# TYPE worker_pool_tasks_total counter")
+	fmt.Fprintf(w, "worker_pool_tasks_total{status=\"succeeded\"} %v\n", report.Succeeded)
+	fmt.Fprintf(w, "worker_pool_tasks_total{status=\"failed\"} %v\n", report.Failed)
+	fmt.Fprintf(w, "worker_pool_tasks_total{status=\"cancelled\"} %v\n", report.Cancelled)
+	fmt.Fprintf(w, "worker_pool_tasks_total{status=\"not_started\"} %v\n", report.NotStarted)
+	fmt.Fprintf(w, "worker_pool_tasks_total{status=\"quarantined\"} %v\n", report.Quarantined)
+
+	fmt.Fprintln(w, "# HELP worker_pool_task_panics_total Panics recovered from tasks.")
+	fmt.Fprintln(w, "# TYPE worker_pool_task_panics_total counter")
+	fmt.Fprintf(w, "worker_pool_task_panics_total %v\n", len(report.Panics))
+
+	fmt.Fprintln(w, "# HELP worker_pool_task_retries_total Retries made by the retry policy.")
+	fmt.Fprintln(w, "# TYPE worker_pool_task_retries_total counter")
+	fmt.Fprintf(w, "worker_pool_task_retries_total %v\n", report.Retries)
+
+	writeHistogram(w, "worker_pool_task_latency_seconds", "Time from submit to result.", p.latency.Snapshot())
+
+	if limiter := p.config.limiter; limiter != nil {
+		fmt.Fprintln(w, "# HELP worker_pool_concurrency_limit Calls allowed in flight by the adaptive limiter.")
+		fmt.Fprintln(w, "# TYPE worker_pool_concurrency_limit gauge")
+		fmt.Fprintf(w, "worker_pool_concurrency_limit %v\n", limiter.Limit())
+	}
+
+	if breaker := p.config.breaker; breaker != nil {
+		stats := breaker.Stats()
+
+		fmt.Fprintln(w, "# HELP worker_pool_circuit_breaker_state Current circuit breaker state.")
+		fmt.Fprintln(w, "# TYPE worker_pool_circuit_breaker_state gauge")
+		for _, state := range []BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen} {
+			current := 0
+			if state == stats.State {
+				current = 1
+			}
+			fmt.Fprintf(w, "worker_pool_circuit_breaker_state{state=\"%v\"} %v\n", state, current)
+		}
+
+		fmt.Fprintln(w, "# HELP worker_pool_circuit_breaker_rejected_total Calls refused by the circuit breaker.")
+		fmt.Fprintln(w, "# TYPE worker_pool_circuit_breaker_rejected_total counter")
+		fmt.Fprintf(w, "worker_pool_circuit_breaker_rejected_total %v\n", stats.Rejected)
+
+		fmt.Fprintln(w, "# HELP worker_pool_task_requeued_total Tasks put back by the circuit breaker.")
+		fmt.Fprintln(w, "# TYPE worker_pool_task_requeued_total counter")
+		fmt.Fprintf(w, "worker_pool_task_requeued_total %v\n", report.Requeued)
+	}
+}
+
+// 'writeHistogram' writes a histogram snapshot with cumulative 'le' buckets in seconds
+func writeHistogram(w io.Writer, name, help string, snapshot HistogramSnapshot) {
+	fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	fmt.Fprintf(w, "# TYPE %v histogram\n", name)
+
+	var cumulative uint64
+	for i, bound := range snapshot.Bounds {
+		cumulative += snapshot.Counts[i]
+		fmt.Fprintf(w, "%v_bucket{le=\"%v\"} %v\n", name, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%v_bucket{le=\"+Inf\"} %v\n", name, snapshot.Count)
+	fmt.Fprintf(w, "%v_sum %v\n", name, strconv.FormatFloat(snapshot.Sum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(w, "%v_count %v\n", name, snapshot.Count)
+}
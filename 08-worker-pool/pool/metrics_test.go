@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHistogram(t *testing.T) {
+	snapshot := HistogramSnapshot{
+		Bounds: []time.Duration{10 * time.Millisecond, 250 * time.Millisecond},
+		Counts: []uint64{1, 2, 3},
+		Sum:    1500 * time.Millisecond,
+		Count:  6,
+	}
+	var b strings.Builder
+	writeHistogram(&b, "latency_seconds", "Latency.", snapshot)
+
+	// the buckets are cumulative, the overflow bucket is '+Inf'
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.01"} 1
+latency_seconds_bucket{le="0.25"} 3
+latency_seconds_bucket{le="+Inf"} 6
+latency_seconds_sum 1.5
+latency_seconds_count 6
+`
+	if b.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", b.String(), want)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "a plain pool",
+			want: []string{
+				"worker_pool_queue_depth 0",
+				`worker_pool_workers{state="busy"} 0`,
+				`worker_pool_workers{state="idle"} 2`,
+				`worker_pool_tasks_total{status="succeeded"} 3`,
+				`worker_pool_tasks_total{status="failed"} 1`,
+				"worker_pool_task_retries_total 0",
+				`worker_pool_task_latency_seconds_bucket{le="+Inf"} 4`,
+				"worker_pool_task_latency_seconds_count 4",
+			},
+			notWant: []string{"worker_pool_concurrency_limit", "worker_pool_circuit_breaker_state"},
+		},
+		{
+			name: "with a retry policy, an adaptive limiter and a circuit breaker",
+			options: []Option{
+				WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}),
+				WithAdaptiveLimit(NewAdaptiveLimiter(LimitPolicy{InitialLimit: 7, MinLimit: 7, MaxLimit: 7})),
+				WithCircuitBreaker(NewCircuitBreaker(BreakerPolicy{})),
+			},
+			want: []string{
+				`worker_pool_tasks_total{status="failed"} 1`,
+				"worker_pool_task_retries_total 1",
+				"worker_pool_concurrency_limit 7",
+				`worker_pool_circuit_breaker_state{state="closed"} 1`,
+				`worker_pool_circuit_breaker_state{state="open"} 0`,
+				"worker_pool_circuit_breaker_rejected_total 0",
+				"worker_pool_task_requeued_total 0",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				if data == 0 {
+					return 0, errors.New("down")
+				}
+				return data, nil
+			}, test.options...)
+			defer p.Wait()
+			defer p.Close()
+			for i := 0; i < 4; i++ {
+				p.Submit(i)
+			}
+			for i := 0; i < 4; i++ {
+				<-p.Results()
+			}
+
+			// scraped while the pool runs with both workers idle
+			recorder := httptest.NewRecorder()
+			p.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+				t.Errorf("got content type %q", contentType)
+			}
+			lines := "\n" + recorder.Body.String()
+			for _, want := range test.want {
+				if !strings.Contains(lines, "\n"+want+"\n") {
+					t.Errorf("no line %q in\n%v", want, recorder.Body.String())
+				}
+			}
+			for _, notWant := range test.notWant {
+				if strings.Contains(lines, notWant) {
+					t.Errorf("unexpected %q in\n%v", notWant, recorder.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"time"
+)
+
+// 'Mode' decides what the pool does when a task returns an error
+type Mode int
+
+const (
+	// 'CollectAll' runs every task and records each failure in the 'Report' (default)
+	CollectAll Mode = iota
+	// 'FailFast' stops dispatch on the first error and cancels in-flight work
+	FailFast
+)
+
+// 'Option' configures a 'Pool' passed to 'New()'
+type Option func(*config)
+
+type config struct {
+	mode        Mode
+	taskTimeout time.Duration
+	autoscale   *AutoscalePolicy
+	retry       RetryPolicy
+	laneWeights [numPriorities]int
+	rateLimiter *RateLimiter
+	journal     taskJournal
+	panics      PanicPolicy
+	scheduler   Scheduler
+	orderWindow int
+	breaker     *CircuitBreaker
+	limiter     *AdaptiveLimiter
+	hedge       *HedgePolicy
+	progress    *ProgressPolicy
+}
+
+func newConfig(options []Option) config {
+	c := config{laneWeights: defaultLaneWeights}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// 'WithMode' sets 'CollectAll' or 'FailFast'
+func WithMode(mode Mode) Option {
+	return func(c *config) {
+		c.mode = mode
+	}
+}
+
+// 'WithTaskTimeout' gives every task its own deadline, the task 'ctx' is cancelled after 'timeout'
+func WithTaskTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.taskTimeout = timeout
+	}
+}
+
+// 'WithAutoscale' starts an autoscaler that resizes the pool from its queue depth and idle workers
+func WithAutoscale(policy AutoscalePolicy) Option {
+	return func(c *config) {
+		c.autoscale = &policy
+	}
+}
+
+// 'WithRetry' retries failed tasks with exponential backoff (see 'RetryPolicy')
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+// 'WithLaneWeights' sets how many tasks each lane may dispatch per round (default 6, 3, 1)
+// a weight below 1 is raised to 1 so no lane is starved
+func WithLaneWeights(high, normal, low int) Option {
+	return func(c *config) {
+		c.laneWeights = [numPriorities]int{max(high, 1), max(normal, 1), max(low, 1)}
+	}
+}
+
+// 'WithRateLimiter' makes every worker wait on 'limiter' before each task attempt (retries included)
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *config) {
+		c.rateLimiter = limiter
+	}
+}
+
+// 'WithPanicPolicy' sets how many times a panicking task is run again before it is quarantined
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(c *config) {
+		c.panics = policy
+	}
+}
+
+// 'WithScheduler' sets how tasks reach the workers: 'ChannelScheduler' (default) or 'WorkStealing'
+func WithScheduler(scheduler Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = scheduler
+	}
+}
+
+// 'WithOrderedResults' sends the results in submission order instead of completion order
+// at most 'window' tasks are submitted and not yet sent as a result, 'Submit' blocks beyond that (see 'ordered.go')
+func WithOrderedResults(window int) Option {
+	return func(c *config) {
+		c.orderWindow = max(window, 1)
+	}
+}
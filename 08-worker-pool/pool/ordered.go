@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"sync"
+)
+
+// workers finish tasks in any order, so 'Results()' are in completion order
+// with 'WithOrderedResults' every task gets a sequence number when it is submitted
+// and a reorder buffer holds each result until the results of all earlier tasks were sent
+// the workers still run in parallel, only the sending of the results waits
+
+// one slow task would make the buffer grow without limit (every later result waits for it)
+// so at most 'window' tasks may be submitted and not yet sent as a result: 'Submit' blocks on the oldest one
+// the buffer never holds more than 'window' results, a 'window' below the number of workers leaves workers idle
+
+// 'ReorderStats' is the current and the largest number of results held back by the reorder buffer
+type ReorderStats struct {
+	Buffered int
+	Peak     int
+}
+
+// 'reorderBuffer' puts results back into submission order
+// 'slots' has one value per task submitted and not yet sent, 'seq' is the next sequence number
+// 'next' is the sequence number of the next result to send, later ones wait in 'held'
+type reorderBuffer[T, R any] struct {
+	slots chan struct{}
+
+	seqMu sync.Mutex
+	seq   uint64
+
+	mu   sync.Mutex
+	next uint64
+	held map[uint64]Result[T, R]
+	peak int
+}
+
+func newReorderBuffer[T, R any](window int) *reorderBuffer[T, R] {
+	return &reorderBuffer[T, R]{
+		slots: make(chan struct{}, window),
+		held:  make(map[uint64]Result[T, R]),
+	}
+}
+
+// 'enqueueOrdered' waits for a free slot in the window, then numbers the job and queues it with 'send'
+// the sequence number is only taken when 'send' succeeds, so a refused task leaves no gap
+func (p *Pool[T, R]) enqueueOrdered(j job[T]) error {
+	select {
+	case p.ordered.slots <- struct{}{}:
+	case <-p.ctx.Done():
+		return ErrStopped
+	case <-p.closing:
+		return ErrStopped
+	}
+
+	p.ordered.seqMu.Lock()
+	defer p.ordered.seqMu.Unlock()
+
+	j.seq = p.ordered.seq
+	if err := p.send(j); err != nil {
+		<-p.ordered.slots
+		return err
+	}
+	p.ordered.seq++
+	return nil
+}
+
+// 'sendOrdered' holds a result until every earlier result was sent, then sends it and the held ones following it
+// the worker completing the oldest task sends for the others, holding 'mu' so the results keep their order
+func (p *Pool[T, R]) sendOrdered(seq uint64, result Result[T, R]) {
+	b := p.ordered
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq != b.next {
+		b.held[seq] = result
+		b.peak = max(b.peak, len(b.held))
+		return
+	}
+
+	for {
+		p.results <- result
+		<-b.slots
+		b.next++
+
+		var ok bool
+		result, ok = b.held[b.next]
+		if !ok {
+			return
+		}
+		delete(b.held, b.next)
+	}
+}
+
+// 'ReorderStats' returns the reorder buffer size (zero without 'WithOrderedResults')
+func (p *Pool[T, R]) ReorderStats() ReorderStats {
+	if p.ordered == nil {
+		return ReorderStats{}
+	}
+	p.ordered.mu.Lock()
+	defer p.ordered.mu.Unlock()
+	return ReorderStats{Buffered: len(p.ordered.held), Peak: p.ordered.peak}
+}
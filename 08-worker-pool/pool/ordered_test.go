@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrderedResults(t *testing.T) {
+	const numTasks = 300
+
+	tests := []struct {
+		scheduler Scheduler
+		workers   int
+		window    int
+	}{
+		{ChannelScheduler, 8, 16},
+		{ChannelScheduler, 8, 1},
+		{ChannelScheduler, 1, 16},
+		{WorkStealing, 8, 16},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v %v workers window %v", test.scheduler, test.workers, test.window), func(t *testing.T) {
+			// later tasks of each group of 5 are faster, so they finish before the earlier ones
+			p := New(test.workers, func(ctx context.Context, data int) (int, error) {
+				time.Sleep(time.Duration(5-data%5) * 100 * time.Microsecond)
+				return data, nil
+			}, WithOrderedResults(test.window), WithScheduler(test.scheduler))
+
+			go func() {
+				defer p.Close()
+				for i := 0; i < numTasks; i++ {
+					p.Submit(i)
+				}
+			}()
+
+			next := 0
+			for result := range p.Results() {
+				if result.Task != next {
+					t.Fatalf("got task %v, want task %v", result.Task, next)
+				}
+				next++
+			}
+			p.Wait()
+			if next != numTasks {
+				t.Errorf("got %v results, want %v", next, numTasks)
+			}
+			if stats := p.ReorderStats(); stats.Buffered != 0 || stats.Peak >= test.window {
+				t.Errorf("the reorder buffer holds %v results (peak %v) with a window of %v", stats.Buffered, stats.Peak, test.window)
+			}
+		})
+	}
+}
+
+// a slow first task holds back the results of the tasks after it, and 'Submit' blocks once the window is full
+func TestOrderedResultsWindow(t *testing.T) {
+	release := make(chan struct{})
+	p := New(4, func(ctx context.Context, data int) (int, error) {
+		if data == 0 {
+			<-release
+		}
+		return data, nil
+	}, WithOrderedResults(4))
+
+	submitted := make(chan int, 10)
+	go func() {
+		defer p.Close()
+		for i := 0; i < 10; i++ {
+			p.Submit(i)
+			submitted <- i
+		}
+	}()
+
+	eventually(t, "3 results held back", func() bool { return p.ReorderStats().Buffered == 3 })
+	select {
+	case <-p.Results():
+		t.Fatal("a result was sent before the result of the first task")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if n := len(submitted); n != 4 {
+		t.Errorf("%v tasks submitted with a window of 4", n)
+	}
+
+	close(release)
+	for i := 0; i < 10; i++ {
+		if result := <-p.Results(); result.Task != i {
+			t.Fatalf("got task %v, want task %v", result.Task, i)
+		}
+	}
+	p.Wait()
+	if peak := p.ReorderStats().Peak; peak != 3 {
+		t.Errorf("the peak is %v, want 3", peak)
+	}
+}
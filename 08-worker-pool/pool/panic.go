@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// a panic inside a task ('apiRequest()') would crash the whole program because nothing recovers it
+// every worker recovers instead: the panic value and stack are recorded against the task,
+// the worker goroutine is replaced by a new one (the pool keeps its size)
+// and the replacement runs the task again, until it panicked 'PanicPolicy.MaxPanics' times and is quarantined
+
+// 'PanicPolicy' decides how often a panicking task is run again
+// 'MaxPanics' is the number of panics after which the task is 'Quarantined' (default 3, 1 = never run again)
+// 'OnPanic' is called for every recovered panic (for logging)
+type PanicPolicy struct {
+	MaxPanics int
+	OnPanic   func(value any, stack []byte)
+}
+
+// 'PanicError' is the error of a quarantined task
+type PanicError struct {
+	Value  any
+	Stack  []byte
+	Panics int
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool: task panicked %v time(s): %v", e.Panics, e.Value)
+}
+
+// 'errPanicked' is the outcome an adaptive limiter (or a circuit breaker) records for a call which panicked
+// the panic itself goes on to the worker, which recovers it
+var errPanicked = errors.New("pool: task panicked")
+
+// 'PanicRecord' is one recovered panic of a task
+// in a batch pool 'Batch' is every task of the batch which panicked and 'Task' is its first one
+type PanicRecord[T any] struct {
+	Task  T
+	Batch []T
+	Value any
+	Stack string
+	Time  time.Time
+}
+
+// 'recordPanic' calls 'OnPanic' and adds 'record' to the report
+func (p *Pool[T, R]) recordPanic(record PanicRecord[T], stack []byte) {
+	if policy := p.config.panics; policy.OnPanic != nil {
+		policy.OnPanic(record.Value, stack)
+	}
+	record.Time = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Panics = append(p.report.Panics, record)
+}
+
+// 'recoverWorker' runs in the deferred recover of a worker that panicked while running 'current'
+// it records the panic, then starts a replacement worker which runs the task again or quarantines it
+func (p *Pool[T, R]) recoverWorker(current *job[T], value any, stack []byte) {
+	if current == nil {
+		// the panic was not inside a task, there is nothing to retry
+		panic(value)
+	}
+
+	policy := p.config.panics
+	if policy.MaxPanics < 1 {
+		policy.MaxPanics = 3
+	}
+
+	j := *current
+	j.panics++
+	p.recordPanic(PanicRecord[T]{Task: j.data, Value: value, Stack: string(stack)}, stack)
+
+	// the replacement is counted before this worker's deferred 'wg.Done' so the pool never looks finished
+	p.wg.Add(1)
+	p.size.Add(1)
+
+	if j.panics < policy.MaxPanics {
+		// 'process' incremented 'busy' for this attempt, the replacement increments it again
+		p.busy.Add(-1)
+		go p.worker(&j)
+		return
+	}
+
+	result := Result[T, R]{
+		Task:     j.data,
+		Err:      &PanicError{Value: value, Stack: stack, Panics: j.panics},
+		Status:   Quarantined,
+		Attempts: j.panics,
+	}
+	go func() {
+		p.finish(j, result, j.submitted)
+		// the tasks waiting behind a quarantined task of a key still run
+		var next *job[T]
+		if j.key != "" {
+			next = p.keys.next(j.key)
+		}
+		p.worker(next)
+	}()
+}
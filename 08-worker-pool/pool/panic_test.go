@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPanicQuarantine(t *testing.T) {
+	// task 0 panics on its first 'panics' calls, the other tasks never panic
+	tests := []struct {
+		name       string
+		maxPanics  int
+		panics     int
+		wantStatus Status
+		wantPanics int
+	}{
+		{"quarantined after the default 3 panics", 0, 10, Quarantined, 3},
+		{"quarantined after 'MaxPanics'", 5, 10, Quarantined, 5},
+		{"never run again with 'MaxPanics' 1", 1, 10, Quarantined, 1},
+		{"run again until it no longer panics", 3, 2, Succeeded, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls, reported atomic.Int64
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				if data == 0 && calls.Add(1) <= int64(test.panics) {
+					var response map[string]int
+					response["id"] = data // assignment to entry in nil map
+				}
+				return data, nil
+			}, WithPanicPolicy(PanicPolicy{MaxPanics: test.maxPanics, OnPanic: func(value any, stack []byte) {
+				reported.Add(1)
+			}}))
+			defer p.Wait()
+			defer p.Close()
+
+			go func() {
+				for i := 0; i < 10; i++ {
+					p.Submit(i)
+				}
+			}()
+			for i := 0; i < 10; i++ {
+				result := <-p.Results()
+				if result.Task != 0 {
+					if result.Status != Succeeded {
+						t.Errorf("task %v ended %v", result.Task, result.Status)
+					}
+					continue
+				}
+				if result.Status != test.wantStatus {
+					t.Errorf("the panicking task ended %v, want %v", result.Status, test.wantStatus)
+				}
+				var panicErr *PanicError
+				if test.wantStatus == Quarantined && (!errors.As(result.Err, &panicErr) || panicErr.Panics != test.wantPanics || len(panicErr.Stack) == 0) {
+					t.Errorf("the quarantined task failed with %v, want a '*PanicError' of %v panics", result.Err, test.wantPanics)
+				}
+			}
+
+			// every panic is recorded and reported, the replaced workers keep the pool at its size
+			report := p.Report()
+			if len(report.Panics) != test.wantPanics || reported.Load() != int64(test.wantPanics) {
+				t.Errorf("%v panics recorded and %v reported, want %v", len(report.Panics), reported.Load(), test.wantPanics)
+			}
+			for _, record := range report.Panics {
+				if record.Task != 0 || record.Stack == "" {
+					t.Errorf("got the record %+v", record)
+				}
+			}
+			if size := p.Size(); size != 2 {
+				t.Errorf("the pool has %v workers after the panics, want 2", size)
+			}
+		})
+	}
+}
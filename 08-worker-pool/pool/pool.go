@@ -0,0 +1,743 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// package 'pool' is the reusable version of the '03-example-worker-pool' 'workerPool()'
+// the design is the same: a buffered channel is the task queue and a 'WaitGroup' tracks the worker goroutines
+// the only difference is the task and result types are generic ('T' for the task, 'R' for the result)
+
+// 'ErrStopped' is returned by 'Submit' once the pool has stopped (first error of a 'FailFast' pool or a cancelled context)
+// or once it no longer accepts tasks ('Close', 'Shutdown' or 'Abort')
+// queued tasks that were never started are reported with 'ErrStopped' and status 'NotStarted'
+var ErrStopped = errors.New("pool: stopped")
+
+// 'Task' is the function each worker calls per submitted value (the '03-example-worker-pool' 'apiRequest()')
+// 'ctx' is cancelled when the pool stops and carries the per-task deadline, so tasks should return early on 'ctx.Done()'
+type Task[T, R any] func(ctx context.Context, data T) (R, error)
+
+// 'Result' pairs a submitted task with its value or error
+// 'Attempts' is how many times the task was called (more than 1 when it was retried)
+// 'Wait' is the time the task spent queued in its lane, 'Latency' is the time from 'Submit' to the result
+type Result[T, R any] struct {
+	Task     T
+	Value    R
+	Err      error
+	Status   Status
+	Attempts int
+	Priority Priority
+	Wait     time.Duration
+	Latency  time.Duration
+}
+
+// 'job' is a submitted task as it travels through the lanes and the buffered channel
+// 'journalID' is the id of its submit record when the pool has a journal (0 otherwise)
+// 'panics' is how many times the task panicked so far, 'seq' is its submission order with 'WithOrderedResults'
+// 'key' is the key of a 'SubmitKey' task (empty otherwise)
+type job[T any] struct {
+	data      T
+	priority  Priority
+	submitted time.Time
+	journalID uint64
+	panics    int
+	seq       uint64
+	key       string
+}
+
+// 'Pool' runs 'numberOfWorkers' goroutines which each process tasks one after another from the buffered channel
+// 'Submit()' tasks, 'Close()' when there are no more tasks, read 'Results()' and 'Wait()' for the workers to finish
+// tasks are queued in one lane per 'Priority' and a dispatcher hands them to the workers (see 'lanes.go')
+type Pool[T, R any] struct {
+	task            Task[T, R]
+	config          config
+	parent          context.Context
+	ctx             context.Context
+	cancel          context.CancelFunc
+	lanes           [numPriorities]chan job[T]
+	bufferedChannel chan job[T]
+	results         chan Result[T, R]
+	wg              sync.WaitGroup
+	closeOnce       sync.Once
+
+	// 'steal' replaces the lanes and 'bufferedChannel' with 'WithScheduler(WorkStealing)' (see 'steal.go')
+	steal *stealScheduler[T]
+	// 'ordered' sends the results in submission order with 'WithOrderedResults' (see 'ordered.go')
+	ordered *reorderBuffer[T, R]
+	// 'keys' holds the tasks waiting behind another task of their key (see 'keys.go')
+	keys keyQueues[T]
+	// 'batch' is the batch task of a 'NewBatch' pool (see 'batch.go')
+	batch *batcher[T, R]
+	// 'hedge' sends second copies of slow calls with 'WithHedging' (see 'hedge.go')
+	hedge *hedger
+	// 'requeued' hands back the tasks an open circuit breaker put aside, 'parked' counts them (see 'breaker.go')
+	// 'parkedJobs', 'waking' and 'unparked' (closed while none is parked) are guarded by 'mu'
+	requeued   chan job[T]
+	parked     atomic.Int64
+	parkedJobs []job[T]
+	waking     bool
+	unparked   chan struct{}
+
+	// 'closing' is closed by 'Close' so a 'Submit' blocked on a full lane gives up
+	// 'submitting' is held (read) by every 'Submit' so 'Close' never closes a lane during a send
+	closing    chan struct{}
+	submitting sync.RWMutex
+
+	// 'shrink' tells one worker to exit after its current task, 'done' is closed once every worker returned
+	shrink chan struct{}
+	done   chan struct{}
+	size   atomic.Int64
+	busy   atomic.Int64
+
+	// 'submitted' and 'completed' count the accepted tasks and their results for 'WithProgress'
+	// 'reported' is closed once the last progress report is written (see 'progress.go')
+	submitted atomic.Int64
+	completed atomic.Int64
+	reported  chan struct{}
+
+	// 'latency' is the time from 'Submit' to the result of every task that ran
+	latency *Histogram
+
+	mu         sync.Mutex
+	closed     bool
+	target     int
+	firstErr   error
+	journalErr error
+	aborted    bool
+	report     Report[T]
+}
+
+// 'New' starts 'numberOfWorkers' goroutines listening on the buffered channel for assigned tasks
+func New[T, R any](numberOfWorkers int, task Task[T, R], options ...Option) *Pool[T, R] {
+	return NewContext(context.Background(), numberOfWorkers, task, options...)
+}
+
+// 'NewContext' is 'New' bound to a parent context
+// cancelling 'ctx' stops 'Submit', cancels in-flight tasks and reports queued tasks as 'NotStarted'
+func NewContext[T, R any](ctx context.Context, numberOfWorkers int, task Task[T, R], options ...Option) *Pool[T, R] {
+	p := newPool(ctx, numberOfWorkers, task, options)
+	p.start()
+	return p
+}
+
+// 'newPool' sets up a pool without starting it
+func newPool[T, R any](ctx context.Context, numberOfWorkers int, task Task[T, R], options []Option) *Pool[T, R] {
+	if numberOfWorkers < 1 {
+		numberOfWorkers = 1
+	}
+
+	p := &Pool[T, R]{
+		task:            task,
+		config:          newConfig(options),
+		parent:          ctx,
+		bufferedChannel: make(chan job[T]),
+		results:         make(chan Result[T, R], numberOfWorkers),
+		closing:         make(chan struct{}),
+		shrink:          make(chan struct{}),
+		requeued:        make(chan job[T]),
+		unparked:        make(chan struct{}),
+		done:            make(chan struct{}),
+		target:          numberOfWorkers,
+		latency:         NewHistogram(nil),
+	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	close(p.unparked)
+
+	// each lane is a buffered channel, the dispatcher moves tasks from the lanes to the workers
+	for i := range p.lanes {
+		p.lanes[i] = make(chan job[T], numberOfWorkers)
+	}
+	if p.config.orderWindow > 0 {
+		p.ordered = newReorderBuffer[T, R](p.config.orderWindow)
+	}
+	if p.config.hedge != nil {
+		p.hedge = newHedger(*p.config.hedge)
+	}
+	return p
+}
+
+// 'start' starts the dispatcher (or the work-stealing deques) and the workers
+func (p *Pool[T, R]) start() {
+	numberOfWorkers := p.target
+	if p.config.scheduler == WorkStealing {
+		p.steal = newStealScheduler[T](numberOfWorkers, &p.parked)
+	} else {
+		go p.dispatch()
+	}
+
+	// once a worker/goroutine is done processing a task, it processes another
+	p.spawn(numberOfWorkers)
+
+	// the results channel is closed once every worker has returned so 'range p.Results()' ends
+	go func() {
+		p.wg.Wait()
+		p.cancel()
+		close(p.done)
+		close(p.results)
+	}()
+
+	if p.config.autoscale != nil {
+		go p.autoscale(*p.config.autoscale)
+	}
+	if p.config.progress != nil {
+		p.reported = make(chan struct{})
+		go p.reportProgress(*p.config.progress)
+	}
+}
+
+// 'spawn' starts 'n' more workers
+func (p *Pool[T, R]) spawn(n int) {
+	for i := 0; i < n; i++ {
+		p.wg.Add(1)
+		p.size.Add(1)
+		go p.worker(nil)
+	}
+}
+
+// while loop the open 'bufferedChannel' and send each task result to 'results'
+// once the pool has stopped, queued tasks are not run, they are reported as 'NotStarted'
+// a 'shrink' signal is only received between tasks so a removed worker always finishes its current 'apiRequest()'
+// a panicking task does not crash the program: the worker recovers, records the panic and is replaced (see 'panic.go')
+// 'first' is a task the worker runs before the queued ones (nil for a new worker):
+// the task a replacement worker runs again, or the next task of a key after a quarantined one
+func (p *Pool[T, R]) worker(first *job[T]) {
+	defer p.wg.Done()
+	defer p.size.Add(-1)
+
+	var current *job[T]
+	defer func() {
+		if value := recover(); value != nil {
+			// a panic of a hedged copy comes with the stack of the goroutine the copy ran in
+			if copied, ok := value.(hedgePanic); ok {
+				p.recoverWorker(current, copied.value, copied.stack)
+				return
+			}
+			p.recoverWorker(current, value, debug.Stack())
+		}
+	}()
+
+	// with 'WorkStealing' the worker has its own deque, its queued tasks move to the other workers when it exits
+	var local *deque[T]
+	if p.steal != nil {
+		local = p.steal.register()
+		defer p.steal.unregister(local)
+	}
+
+	if first != nil {
+		p.processKeyed(*first, &current)
+	}
+
+	for {
+		j, ok := p.next(local)
+		if !ok {
+			return
+		}
+		if p.batch != nil {
+			p.processBatch(p.collect(j))
+			continue
+		}
+		p.processKeyed(j, &current)
+	}
+}
+
+// 'processKeyed' runs a job, then every task that waited behind it for the same key (see 'keys.go')
+// 'current' is the job running, so a panic is recorded against the right task
+// a job put back by the circuit breaker keeps its key busy, the next task of the key runs after it
+func (p *Pool[T, R]) processKeyed(j job[T], current **job[T]) {
+	for {
+		*current = &j
+		finished := p.process(j)
+		*current = nil
+
+		if j.key == "" || !finished {
+			return
+		}
+		next := p.keys.next(j.key)
+		if next == nil {
+			return
+		}
+		j = *next
+	}
+}
+
+// 'next' is the next task of a worker, false once the worker should exit (shrink or closed and drained)
+// a closed pool is only drained once no task is parked by the circuit breaker
+func (p *Pool[T, R]) next(local *deque[T]) (job[T], bool) {
+	if p.steal != nil {
+		return p.steal.take(local)
+	}
+
+	select {
+	case <-p.shrink:
+		var zero job[T]
+		return zero, false
+	case j := <-p.requeued:
+		return j, true
+	case j, open := <-p.bufferedChannel:
+		if open {
+			return j, true
+		}
+		return p.parkedJob()
+	}
+}
+
+// 'process' runs one job and sends its result, false when the circuit breaker put the job back instead
+func (p *Pool[T, R]) process(j job[T]) bool {
+	p.busy.Add(1)
+
+	started := time.Now()
+	var result Result[T, R]
+	if p.ctx.Err() != nil {
+		result = Result[T, R]{Task: j.data, Err: ErrStopped, Status: NotStarted}
+	} else {
+		result = p.run(j.data)
+	}
+
+	if p.requeue(result) {
+		p.busy.Add(-1)
+		p.park(j)
+		return false
+	}
+	p.finish(j, result, started)
+	return true
+}
+
+// 'finish' records a result and sends it to 'results'
+func (p *Pool[T, R]) finish(j job[T], result Result[T, R], started time.Time) {
+	result.Priority = j.priority
+	result.Wait = started.Sub(j.submitted)
+	result.Latency = time.Since(j.submitted)
+
+	p.record(result)
+	p.journalCompleted(j, result.Status)
+	p.busy.Add(-1)
+	if p.ordered != nil {
+		p.sendOrdered(j.seq, result)
+		return
+	}
+	p.results <- result
+}
+
+// 'run' calls the task with the pool context (plus the per-task deadline) and classifies the outcome
+// with a 'RetryPolicy' a failed task is called again after a backoff until it succeeds or runs out of attempts
+func (p *Pool[T, R]) run(data T) Result[T, R] {
+	retry := p.config.retry
+	if retry.Budget != nil {
+		retry.Budget.deposit()
+	}
+
+	result := Result[T, R]{Task: data}
+	for {
+		result.Attempts++
+		result.Value, result.Err = p.attempt(data)
+
+		if result.Err == nil || p.ctx.Err() != nil {
+			break
+		}
+		// a refused attempt is not retried, the breaker stays open for its cool-down
+		if result.Attempts >= retry.MaxAttempts || !retry.retryable(result.Err) || errors.Is(result.Err, ErrCircuitOpen) {
+			break
+		}
+		if retry.Budget != nil && !retry.Budget.withdraw() {
+			break
+		}
+		if sleep(p.ctx, retry.backoff(result.Attempts)) != nil {
+			break
+		}
+	}
+
+	switch {
+	case result.Err == nil:
+		result.Status = Succeeded
+	case p.ctx.Err() != nil:
+		// the pool stopped while the task was in flight
+		result.Status = Cancelled
+	default:
+		// includes 'context.DeadlineExceeded' from the per-task deadline
+		result.Status = Failed
+	}
+	return result
+}
+
+// 'attempt' is one call of the task, each attempt gets its own per-task deadline
+// with 'WithHedging' a slow call gets a second copy, each copy is guarded on its own
+func (p *Pool[T, R]) attempt(data T) (R, error) {
+	if p.hedge != nil {
+		return p.hedged(data)
+	}
+	var value R
+	err := p.guard(p.ctx, func() error {
+		var err error
+		value, err = p.callTask(p.ctx, data)
+		return err
+	})
+	return value, err
+}
+
+// 'guard' runs one call of the task ('call')
+// with a 'CircuitBreaker' the call must be allowed first and its outcome (or its panic) is recorded
+// with a 'RateLimiter' the worker then waits for a token, with an 'AdaptiveLimiter' for a slot
+// cancelling 'ctx' stops the waits, a hedge copy which lost is cancelled that way
+func (p *Pool[T, R]) guard(ctx context.Context, call func() error) error {
+	breaker := p.config.breaker
+	if breaker == nil {
+		return p.limit(ctx, call)
+	}
+
+	generation, err := breaker.allow()
+	if err != nil {
+		return err
+	}
+	// a panic is recorded as a failure, a half-open breaker would otherwise wait for the trial forever
+	panicked := true
+	defer func() {
+		if panicked {
+			breaker.done(generation, errPanicked)
+		}
+	}()
+	err = p.limit(ctx, call)
+	panicked = false
+	breaker.done(generation, err)
+	return err
+}
+
+// 'limit' waits for the rate limiter and for a slot of the adaptive limiter, then runs 'call'
+// the slot is given back even when 'call' panics, the panic counts as a failed call
+func (p *Pool[T, R]) limit(ctx context.Context, call func() error) (err error) {
+	if p.config.rateLimiter != nil {
+		if err := p.config.rateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	limiter := p.config.limiter
+	if limiter == nil {
+		return call()
+	}
+	started, err := limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	panicked := true
+	defer func() {
+		if panicked {
+			limiter.release(started, errPanicked)
+			return
+		}
+		limiter.release(started, err)
+	}()
+	err = call()
+	panicked = false
+	return err
+}
+
+// 'callTask' calls the task with the per-task deadline
+func (p *Pool[T, R]) callTask(ctx context.Context, data T) (R, error) {
+	if p.config.taskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.taskTimeout)
+		defer cancel()
+	}
+	return p.task(ctx, data)
+}
+
+// 'record' adds a result to the report and stops dispatch on the first error of a 'FailFast' pool
+func (p *Pool[T, R]) record(result Result[T, R]) {
+	if result.Status != NotStarted {
+		p.latency.Observe(result.Latency)
+	}
+	p.completed.Add(1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result.Attempts > 1 {
+		p.report.Retries += result.Attempts - 1
+	}
+
+	switch result.Status {
+	case Succeeded:
+		p.report.Succeeded++
+	case Cancelled:
+		p.report.Cancelled++
+	case NotStarted:
+		p.report.NotStarted++
+	case Quarantined:
+		p.report.Quarantined++
+	case Failed:
+		p.report.Failed++
+		p.report.Failures = append(p.report.Failures, Failure[T]{Task: result.Task, Err: result.Err, Attempts: result.Attempts})
+
+		if p.config.mode == FailFast && p.firstErr == nil {
+			p.firstErr = result.Err
+			// cancel in-flight work, tasks see 'ctx.Done()'
+			p.cancel()
+		}
+	}
+}
+
+// 'Submit' writes a task to the 'Normal' priority lane
+// 'sends' to a buffered channel are blocked only when the buffer is full (all workers busy and lane full)
+// a stopped or closed pool no longer accepts tasks and 'Submit' returns 'ErrStopped'
+func (p *Pool[T, R]) Submit(data T) error {
+	return p.SubmitPriority(data, Normal)
+}
+
+// 'SubmitPriority' writes a task to the lane of 'priority' ('High', 'Normal' or 'Low')
+// with a journal the task is appended to the journal before it is queued
+func (p *Pool[T, R]) SubmitPriority(data T, priority Priority) error {
+	if p.ctx.Err() != nil {
+		return ErrStopped
+	}
+	select {
+	case <-p.closing:
+		return ErrStopped
+	default:
+	}
+
+	return p.submitJob(job[T]{data: data, priority: priority})
+}
+
+// 'submitJob' appends a job to the journal (with 'WithJournal') and queues it
+func (p *Pool[T, R]) submitJob(j job[T]) error {
+	if err := p.journalSubmitted(&j); err != nil {
+		return err
+	}
+	return p.enqueue(j)
+}
+
+// 'journalSubmitted' appends a job to the journal and keeps its journal id
+func (p *Pool[T, R]) journalSubmitted(j *job[T]) error {
+	if p.config.journal == nil {
+		return nil
+	}
+	id, err := p.config.journal.submitted(j.data)
+	if err != nil {
+		return err
+	}
+	j.journalID = id
+	return nil
+}
+
+// 'enqueue' writes a job to its lane
+func (p *Pool[T, R]) enqueue(j job[T]) error {
+	p.submitting.RLock()
+	defer p.submitting.RUnlock()
+
+	// checked first because 'select' picks randomly between a free lane and a closed 'closing'
+	select {
+	case <-p.closing:
+		return ErrStopped
+	default:
+	}
+
+	j.submitted = time.Now()
+
+	var err error
+	if p.ordered != nil {
+		err = p.enqueueOrdered(j)
+	} else {
+		err = p.send(j)
+	}
+	if err == nil {
+		p.submitted.Add(1)
+	}
+	return err
+}
+
+// 'send' writes a job to its lane (or a worker deque with 'WorkStealing')
+func (p *Pool[T, R]) send(j job[T]) error {
+	if p.steal != nil {
+		return p.steal.push(j, p.ctx.Done(), p.closing)
+	}
+
+	select {
+	case p.lanes[j.priority.lane()] <- j:
+		return nil
+	case <-p.ctx.Done():
+		return ErrStopped
+	case <-p.closing:
+		return ErrStopped
+	}
+}
+
+// 'Recover' queues the tasks the journal recorded as submitted but never finished (see 'WithJournal')
+// call it once after a restart, before submitting new tasks, it returns how many tasks were queued
+func (p *Pool[T, R]) Recover() (int, error) {
+	if p.config.journal == nil {
+		return 0, nil
+	}
+	tasks, err := p.config.journal.recovered()
+	if err != nil {
+		return 0, err
+	}
+	for i, task := range tasks {
+		data, ok := task.data.(T)
+		if !ok {
+			return i, fmt.Errorf("pool: journal task %v is a %T, not a %T", task.id, task.data, data)
+		}
+		if err := p.enqueue(job[T]{data: data, priority: Normal, journalID: task.id}); err != nil {
+			return i, err
+		}
+	}
+	return len(tasks), nil
+}
+
+// 'journalCompleted' marks a finished ('Succeeded', 'Failed' or 'Quarantined') task done in the journal
+// 'Cancelled' and 'NotStarted' tasks stay pending so they run again after a restart
+func (p *Pool[T, R]) journalCompleted(j job[T], status Status) {
+	if p.config.journal == nil || j.journalID == 0 {
+		return
+	}
+	if status != Succeeded && status != Failed && status != Quarantined {
+		return
+	}
+	if err := p.config.journal.completed(j.journalID, status); err != nil {
+		p.mu.Lock()
+		if p.journalErr == nil {
+			p.journalErr = err
+		}
+		p.mu.Unlock()
+	}
+}
+
+// 'Results' is the typed results channel, one value per accepted task in completion order
+// (in submission order with 'WithOrderedResults')
+// results must be read (drained) otherwise workers block once the results buffer is full
+func (p *Pool[T, R]) Results() <-chan Result[T, R] {
+	return p.results
+}
+
+// 'Close' closes the lanes, the dispatcher hands out the queued tasks and then closes the buffered channel
+// workers finish the queued tasks and then return
+// it is safe to call 'Close' more than once and while other goroutines still 'Submit' (they get 'ErrStopped')
+func (p *Pool[T, R]) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+		p.submitting.Lock()
+		defer p.submitting.Unlock()
+
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		for _, lane := range p.lanes {
+			close(lane)
+		}
+		if p.steal != nil {
+			p.steal.close()
+		}
+	})
+}
+
+// 'Resize' grows or shrinks the number of workers while the pool runs ('n' is at least 1)
+// new workers start right away, removed workers exit once they finish their current task
+// resizing a closed pool does nothing, with 'WithAutoscale' the autoscaler may resize again on its next tick
+func (p *Pool[T, R]) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	delta := n - p.target
+	p.target = n
+	if p.steal != nil {
+		p.steal.resize(n)
+	}
+
+	if delta > 0 {
+		p.spawn(delta)
+		return
+	}
+
+	if p.steal != nil {
+		p.steal.stop(-delta)
+		return
+	}
+
+	// each 'shrink' signal is picked up by whichever worker is idle first
+	go func(stops int) {
+		for i := 0; i < stops; i++ {
+			select {
+			case p.shrink <- struct{}{}:
+			case <-p.done:
+				return
+			}
+		}
+	}(-delta)
+}
+
+// 'Size' is the number of running workers (it reaches the 'Resize' target once removed workers finish their task)
+func (p *Pool[T, R]) Size() int {
+	return int(p.size.Load())
+}
+
+// 'Busy' is the number of workers currently running a task, the rest are idle
+func (p *Pool[T, R]) Busy() int {
+	return int(p.busy.Load())
+}
+
+// 'QueueDepth' is the number of tasks waiting in the lanes or deques (the '03-example-worker-pool' 'len(bufferedChannel)')
+func (p *Pool[T, R]) QueueDepth() int {
+	if p.steal != nil {
+		return p.steal.depth() + p.keys.depth()
+	}
+	depth := p.keys.depth()
+	for _, lane := range p.lanes {
+		depth += len(lane)
+	}
+	return depth
+}
+
+// 'targetSize' is the size the pool is converging to after 'Resize'
+func (p *Pool[T, R]) targetSize() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.target
+}
+
+// 'Wait' blocks until every worker has returned (call 'Close' first, Promise.all())
+// it returns the first task error of a 'FailFast' pool, the parent context error, 'ErrAborted' or the first journal write error
+func (p *Pool[T, R]) Wait() error {
+	p.wg.Wait()
+	if p.reported != nil {
+		<-p.reported
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.firstErr != nil {
+		return p.firstErr
+	}
+	if err := p.parent.Err(); err != nil {
+		return err
+	}
+	if p.aborted {
+		return ErrAborted
+	}
+	return p.journalErr
+}
+
+// 'Report' returns the per-status counts, the per-task failures and the latency histogram recorded so far
+func (p *Pool[T, R]) Report() Report[T] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	report := p.report
+	report.Failures = append([]Failure[T](nil), p.report.Failures...)
+	report.Panics = append([]PanicRecord[T](nil), p.report.Panics...)
+	report.Latency = p.latency.Snapshot()
+	return report
+}
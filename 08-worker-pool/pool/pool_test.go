@@ -0,0 +1,154 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 2 workers run task 0 and task 1, task 0 fails once task 1 started, task 1 takes 50ms unless it is cancelled
+// 'FailFast' cancels task 1 and reports the queued tasks as not started, 'CollectAll' runs every task
+func TestModes(t *testing.T) {
+	const numTasks = 20
+	errDown := errors.New("down")
+
+	tests := []struct {
+		mode      Mode
+		wantTask1 Status
+		wantRest  Status
+		wantErr   error
+	}{
+		{CollectAll, Succeeded, Succeeded, nil},
+		{FailFast, Cancelled, NotStarted, errDown},
+	}
+	for _, test := range tests {
+		name := "collect all"
+		if test.mode == FailFast {
+			name = "fail fast"
+		}
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{})
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				switch data {
+				case 0:
+					<-started
+					return 0, errDown
+				case 1:
+					close(started)
+					select {
+					case <-time.After(50 * time.Millisecond):
+					case <-ctx.Done():
+						return 0, ctx.Err()
+					}
+				}
+				return data, nil
+			}, WithMode(test.mode))
+
+			accepted := make(chan int, 1)
+			go func() {
+				defer p.Close()
+				n := 0
+				for i := 0; i < numTasks; i++ {
+					if p.Submit(i) == nil {
+						n++
+					}
+				}
+				accepted <- n
+			}()
+
+			counts := make(map[Status]int)
+			for result := range p.Results() {
+				counts[result.Status]++
+				want := test.wantRest
+				switch result.Task {
+				case 0:
+					want = Failed
+				case 1:
+					want = test.wantTask1
+				}
+				if result.Status != want {
+					t.Errorf("task %v ended %v, want %v", result.Task, result.Status, want)
+				}
+			}
+			if err := p.Wait(); !errors.Is(err, test.wantErr) {
+				t.Errorf("Wait returned %v, want %v", err, test.wantErr)
+			}
+
+			// a 'FailFast' pool refuses the tasks submitted after the failure
+			n := <-accepted
+			if test.mode == CollectAll && n != numTasks {
+				t.Errorf("%v of %v tasks accepted", n, numTasks)
+			}
+			report := p.Report()
+			got := [4]int{report.Succeeded, report.Failed, report.Cancelled, report.NotStarted}
+			want := [4]int{counts[Succeeded], counts[Failed], counts[Cancelled], counts[NotStarted]}
+			if got != want {
+				t.Errorf("the report counts %v, the results %v", got, want)
+			}
+			if report.Succeeded+report.Failed+report.Cancelled+report.NotStarted != n {
+				t.Errorf("the report counts %v tasks, %v were accepted", got, n)
+			}
+		})
+	}
+}
+
+// a task which runs past its own deadline fails, the pool goes on with the other tasks
+func TestTaskTimeout(t *testing.T) {
+	p := New(2, func(ctx context.Context, data int) (int, error) {
+		if data == 0 {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return data, nil
+	}, WithTaskTimeout(20*time.Millisecond))
+
+	go func() {
+		defer p.Close()
+		for i := 0; i < 10; i++ {
+			p.Submit(i)
+		}
+	}()
+	for result := range p.Results() {
+		switch {
+		case result.Task == 0 && (result.Status != Failed || !errors.Is(result.Err, context.DeadlineExceeded)):
+			t.Errorf("the slow task ended %v: %v, want failed with the deadline", result.Status, result.Err)
+		case result.Task != 0 && result.Status != Succeeded:
+			t.Errorf("task %v ended %v: %v", result.Task, result.Status, result.Err)
+		}
+	}
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+}
+
+// cancelling the parent context cancels the task in flight and stops 'Submit'
+func TestParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	p := NewContext(ctx, 1, func(ctx context.Context, data int) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if err := p.Submit(0); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+
+	if err := p.Submit(1); !errors.Is(err, ErrStopped) {
+		t.Errorf("Submit after the cancel returned %v, want ErrStopped", err)
+	}
+	p.Close()
+	for result := range p.Results() {
+		if result.Status != Cancelled {
+			t.Errorf("task %v ended %v, want cancelled", result.Task, result.Status)
+		}
+	}
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait returned %v, want context.Canceled", err)
+	}
+}
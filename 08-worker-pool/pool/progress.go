@@ -0,0 +1,224 @@
+package pool
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// a run of a million tasks shows nothing until the last result, a progress reporter shows how far it is every 'Interval':
+// -the completed tasks out of the total, the recent rate (tasks/s over the last 10 intervals) and the ETA at that rate
+// -the tasks in flight (busy workers) and queued
+// the reporter draws a progress line on a terminal, writes structured log lines to anything else (a file, a pipe, CI logs)
+// and calls 'OnProgress' for programs drawing their own (a web page, a TUI)
+
+// 'ProgressFormat' is how a progress reporter writes to 'ProgressPolicy.Output'
+type ProgressFormat int
+
+const (
+	// 'ProgressAuto' draws a line when 'Output' is a terminal and logs otherwise (default)
+	ProgressAuto ProgressFormat = iota
+	// 'ProgressLine' redraws one line ('\r'), for a terminal
+	ProgressLine
+	// 'ProgressLog' writes one 'log/slog' text line per interval
+	ProgressLog
+)
+
+func (f ProgressFormat) String() string {
+	switch f {
+	case ProgressAuto:
+		return "auto"
+	case ProgressLine:
+		return "line"
+	case ProgressLog:
+		return "log"
+	}
+	return "unknown"
+}
+
+// 'ProgressPolicy' configures a progress reporter, zero fields get the defaults in brackets
+// -'Total' is the number of tasks the run will submit (0 = the tasks accepted so far, the ETA is then a lower bound)
+// -'Interval' is how often the progress is reported (1s)
+// -'Output' is where it is written (nil = not written, only 'OnProgress' is called)
+// -'Format' is how it is written to 'Output' ('ProgressAuto')
+// -'OnProgress' is called with every report from the reporter goroutine, the last one has 'Done' set
+type ProgressPolicy struct {
+	Total      int
+	Interval   time.Duration
+	Output     io.Writer
+	Format     ProgressFormat
+	OnProgress func(progress Progress)
+}
+
+// 'Progress' is one report
+// 'Rate' is the tasks completed per second over the last 10 intervals, 'ETA' the time left at that rate (0 = unknown)
+type Progress struct {
+	Time      time.Time
+	Elapsed   time.Duration
+	Completed int
+	Total     int
+	InFlight  int
+	Queued    int
+	Rate      float64
+	ETA       time.Duration
+	Done      bool
+}
+
+// 'Percent' is the completed share of the total (0-100)
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return 0
+	}
+	return 100 * float64(p.Completed) / float64(p.Total)
+}
+
+// 'progressWindow' is the number of reports the rate is computed over
+const progressWindow = 10
+
+// 'progressSample' is the completed count at one report
+type progressSample struct {
+	time      time.Time
+	completed int
+}
+
+// 'reportProgress' runs until every worker has returned, then reports a last time with 'Done' and closes 'reported'
+func (p *Pool[T, R]) reportProgress(policy ProgressPolicy) {
+	defer close(p.reported)
+
+	if policy.Interval <= 0 {
+		policy.Interval = time.Second
+	}
+	format := policy.Format
+	if format == ProgressAuto {
+		format = ProgressLog
+		if isTerminal(policy.Output) {
+			format = ProgressLine
+		}
+	}
+	var logger *slog.Logger
+	if policy.Output != nil && format == ProgressLog {
+		logger = slog.New(slog.NewTextHandler(policy.Output, nil))
+	}
+
+	started := time.Now()
+	samples := []progressSample{{time: started}}
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		done := false
+		select {
+		case now = <-ticker.C:
+		case <-p.done:
+			now, done = time.Now(), true
+		}
+
+		progress := p.progress(policy.Total, started, now, samples[0])
+		progress.Done = done
+		samples = append(samples, progressSample{time: now, completed: progress.Completed})
+		if len(samples) > progressWindow+1 {
+			samples = samples[1:]
+		}
+
+		if policy.OnProgress != nil {
+			policy.OnProgress(progress)
+		}
+		switch {
+		case policy.Output == nil:
+		case format == ProgressLine:
+			fmt.Fprint(policy.Output, "\r"+progressLine(progress)+"\x1b[K")
+			if done {
+				fmt.Fprintln(policy.Output)
+			}
+		default:
+			logProgress(logger, progress)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// 'progress' is the state of the pool at 'now', the rate is measured since 'oldest'
+func (p *Pool[T, R]) progress(total int, started, now time.Time, oldest progressSample) Progress {
+	progress := Progress{
+		Time:      now,
+		Elapsed:   now.Sub(started),
+		Completed: int(p.completed.Load()),
+		Total:     total,
+		InFlight:  p.Busy(),
+		Queued:    p.QueueDepth(),
+	}
+	if progress.Total <= 0 {
+		progress.Total = int(p.submitted.Load())
+	}
+	if elapsed := now.Sub(oldest.time).Seconds(); elapsed > 0 {
+		progress.Rate = float64(progress.Completed-oldest.completed) / elapsed
+	}
+	if remaining := progress.Total - progress.Completed; remaining > 0 && progress.Rate > 0 {
+		progress.ETA = time.Duration(float64(remaining) / progress.Rate * float64(time.Second))
+	}
+	return progress
+}
+
+// 'progressLine' is a report as one terminal line:
+//
+//	[=========>                    ]  33.2%  332000/1000000  41012/s  eta 16.3s  in flight 100  queued 100
+func progressLine(progress Progress) string {
+	const width = 30
+	filled := min(int(progress.Percent()*width/100), width)
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+
+	eta := "-"
+	switch {
+	case progress.Done:
+		eta = "done"
+	case progress.ETA > 0:
+		eta = progress.ETA.Round(100 * time.Millisecond).String()
+	}
+	return fmt.Sprintf("[%v] %5.1f%%  %v/%v  %.0f/s  eta %v  in flight %v  queued %v",
+		bar, progress.Percent(), progress.Completed, progress.Total, progress.Rate, eta, progress.InFlight, progress.Queued)
+}
+
+// 'logProgress' writes a report as one structured line:
+//
+//	time=2026-10-18T10:00:01.000Z level=INFO msg=progress completed=41012 total=1000000 percent=4.1 rate=41012 eta=23.4s in_flight=100 queued=100
+func logProgress(logger *slog.Logger, progress Progress) {
+	msg := "progress"
+	if progress.Done {
+		msg = "progress done"
+	}
+	logger.Info(msg,
+		"completed", progress.Completed,
+		"total", progress.Total,
+		"percent", fmt.Sprintf("%.1f", progress.Percent()),
+		"rate", fmt.Sprintf("%.0f", progress.Rate),
+		"eta", progress.ETA.Round(100*time.Millisecond),
+		"in_flight", progress.InFlight,
+		"queued", progress.Queued,
+		"elapsed", progress.Elapsed.Round(time.Millisecond),
+	)
+}
+
+// 'isTerminal' reports whether 'w' is a terminal, not a file, a pipe or another character device like '/dev/null'
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// 'WithProgress' reports the progress of the pool every 'Interval' until every worker has returned
+// 'Wait' returns once the last report is written
+func WithProgress(policy ProgressPolicy) Option {
+	return func(c *config) {
+		c.progress = &policy
+	}
+}
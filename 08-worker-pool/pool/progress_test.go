@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressRate(t *testing.T) {
+	p := New(4, func(ctx context.Context, data int) (int, error) {
+		return data, nil
+	})
+	defer p.Wait()
+	defer p.Close()
+	for i := 0; i < 10; i++ {
+		p.Submit(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-p.Results()
+	}
+
+	// 10 tasks completed, the rate is measured since the oldest sample
+	started := time.Now()
+	tests := []struct {
+		name        string
+		total       int
+		oldest      progressSample
+		wantTotal   int
+		wantRate    float64
+		wantETA     time.Duration
+		wantPercent float64
+	}{
+		{"10 tasks in 2s, 30 left", 40, progressSample{time: started}, 40, 5, 6 * time.Second, 25},
+		{"6 tasks in the last second, 30 left", 40, progressSample{time: started.Add(time.Second), completed: 4}, 40, 6, 5 * time.Second, 25},
+		{"no task since the oldest sample, the ETA is unknown", 40, progressSample{time: started, completed: 10}, 40, 0, 0, 25},
+		{"no total, the tasks accepted so far are the total", 0, progressSample{time: started}, 10, 5, 0, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			progress := p.progress(test.total, started, started.Add(2*time.Second), test.oldest)
+			if progress.Completed != 10 || progress.Total != test.wantTotal || progress.Elapsed != 2*time.Second {
+				t.Errorf("got %v/%v after %v, want 10/%v after 2s", progress.Completed, progress.Total, progress.Elapsed, test.wantTotal)
+			}
+			if progress.Rate != test.wantRate || progress.ETA != test.wantETA || progress.Percent() != test.wantPercent {
+				t.Errorf("got %v/s, eta %v, %v%%, want %v/s, eta %v, %v%%",
+					progress.Rate, progress.ETA, progress.Percent(), test.wantRate, test.wantETA, test.wantPercent)
+			}
+		})
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	progress := Progress{Completed: 332, Total: 1000, Rate: 41.2, ETA: 16345 * time.Millisecond, InFlight: 4, Queued: 8}
+	want := "[=========>                    ]  33.2%  332/1000  41/s  eta 16.3s  in flight 4  queued 8"
+	if got := progressLine(progress); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	progress = Progress{Completed: 1000, Total: 1000, Rate: 41.2, Done: true}
+	want = "[==============================] 100.0%  1000/1000  41/s  eta done  in flight 0  queued 0"
+	if got := progressLine(progress); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestProgressOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ProgressFormat
+		want   []string
+	}{
+		{"a line", ProgressLine, []string{"\r[", "  5/5  ", "eta done", "\x1b[K\n"}},
+		{"log lines", ProgressLog, []string{"level=INFO msg=\"progress done\" completed=5 total=5 percent=100.0"}},
+		// a buffer is no terminal
+		{"auto", ProgressAuto, []string{"level=INFO msg=\"progress done\" completed=5 total=5 percent=100.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				return data, nil
+			}, WithProgress(ProgressPolicy{Total: 5, Interval: time.Hour, Output: &b, Format: test.format}))
+			go func() {
+				defer p.Close()
+				for i := 0; i < 5; i++ {
+					p.Submit(i)
+				}
+			}()
+			for range p.Results() {
+			}
+			p.Wait()
+
+			// the interval is never reached, the only report is the last one
+			for _, want := range test.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("no %q in %q", want, b.String())
+				}
+			}
+			if test.format != ProgressLine && strings.Contains(b.String(), "\r") {
+				t.Errorf("a line was drawn in %q", b.String())
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	if isTerminal(null) {
+		t.Errorf("%v is a terminal", os.DevNull)
+	}
+	if isTerminal(&bytes.Buffer{}) {
+		t.Error("a buffer is a terminal")
+	}
+}
@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 'RateLimiter' is a token bucket shared by every worker
+// the bucket refills at 'rate' tokens per second and holds at most 'burst' tokens
+// each task attempt takes one token, when the bucket is empty the worker waits for the next token
+// an optional 'maxPerSecond' cap also limits calls per wall-clock second (0 = no cap)
+type RateLimiter struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	maxPerSecond int
+	window       time.Time
+	windowCount  int
+
+	acquired int
+	waited   time.Duration
+}
+
+// 'RateLimiterStats' is how many tokens were handed out and how long workers waited for them in total
+type RateLimiterStats struct {
+	Acquired int
+	Waited   time.Duration
+}
+
+// 'NewRateLimiter' returns a full bucket of 'burst' tokens refilled at 'rate' tokens per second
+func NewRateLimiter(rate float64, burst int, maxPerSecond int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:         rate,
+		burst:        float64(burst),
+		tokens:       float64(burst),
+		last:         time.Now(),
+		maxPerSecond: maxPerSecond,
+	}
+}
+
+// 'Wait' blocks until a token is available or 'ctx' is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+
+	for {
+		delay := l.reserve(time.Now())
+		if delay == 0 {
+			break
+		}
+		if err := sleep(ctx, delay); err != nil {
+			l.addWaited(time.Since(start))
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	l.acquired++
+	l.waited += time.Since(start)
+	l.mu.Unlock()
+	return nil
+}
+
+// 'reserve' takes a token and returns 0, or returns how long to wait before trying again
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// refill the bucket for the time since the last call
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		if l.rate <= 0 {
+			return time.Second
+		}
+		return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+
+	// the per-second cap counts calls in the current wall-clock second
+	if l.maxPerSecond > 0 {
+		window := now.Truncate(time.Second)
+		if !window.Equal(l.window) {
+			l.window = window
+			l.windowCount = 0
+		}
+		if l.windowCount >= l.maxPerSecond {
+			return window.Add(time.Second).Sub(now)
+		}
+		l.windowCount++
+	}
+
+	l.tokens--
+	return 0
+}
+
+func (l *RateLimiter) addWaited(waited time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waited += waited
+}
+
+// 'Stats' returns the tokens handed out and the total time workers spent waiting on the limiter
+func (l *RateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Acquired: l.acquired, Waited: l.waited}
+}
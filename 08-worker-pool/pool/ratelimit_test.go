@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// each step reserves a token 'at' after the start, 'want' is the wait it is told to do (0 = it got the token)
+	type step struct {
+		at   time.Duration
+		want time.Duration
+	}
+	tests := []struct {
+		name                string
+		rate                float64
+		burst, maxPerSecond int
+		steps               []step
+	}{
+		{"a full bucket lets a burst through", 10, 3, 0, []step{{0, 0}, {0, 0}, {0, 0}, {0, 100 * time.Millisecond}}},
+		{"an empty bucket refills at 'rate'", 10, 1, 0, []step{{0, 0}, {0, 100 * time.Millisecond}, {50 * time.Millisecond, 50 * time.Millisecond}, {100 * time.Millisecond, 0}}},
+		{"the bucket holds at most 'burst'", 10, 2, 0, []step{{0, 0}, {0, 0}, {10 * time.Second, 0}, {10 * time.Second, 0}, {10 * time.Second, 100 * time.Millisecond}}},
+		{"a burst below 1 is 1", 10, 0, 0, []step{{0, 0}, {0, 100 * time.Millisecond}}},
+		{"no refill without a rate", 0, 1, 0, []step{{0, 0}, {time.Hour, time.Second}}},
+		{"'maxPerSecond' caps a wall-clock second", 1000, 100, 2, []step{{0, 0}, {0, 0}, {300 * time.Millisecond, 700 * time.Millisecond}, {time.Second, 0}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := NewRateLimiter(test.rate, test.burst, test.maxPerSecond)
+			l.last = start
+			for i, step := range test.steps {
+				if got := l.reserve(start.Add(step.at)); got != step.want {
+					t.Fatalf("step %v: got %v, want %v", i, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(100, 2, 0)
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 tokens of the burst and 3 refilled at 10ms each
+	if elapsed := time.Since(started); elapsed < 25*time.Millisecond {
+		t.Errorf("5 tokens took %v, want about 30ms", elapsed)
+	}
+	if stats := l.Stats(); stats.Acquired != 5 {
+		t.Errorf("%v tokens acquired, want 5", stats.Acquired)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewRateLimiter(0, 1, 0).Wait(ctx); err != nil {
+		t.Errorf("the first token of a full bucket returned %v", err)
+	}
+	empty := NewRateLimiter(0, 1, 0)
+	empty.Wait(context.Background())
+	if err := empty.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("waiting on an empty bucket with a cancelled context returned %v", err)
+	}
+}
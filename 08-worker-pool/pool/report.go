@@ -0,0 +1,64 @@
+package pool
+
+// 'Status' is the outcome of one submitted task
+type Status int
+
+const (
+	// 'Succeeded' the task ran and returned a nil error
+	Succeeded Status = iota
+	// 'Failed' the task ran and returned an error (including its own per-task deadline)
+	Failed
+	// 'Cancelled' the task was in flight when the pool stopped
+	Cancelled
+	// 'NotStarted' the task was queued but the pool stopped before a worker ran it
+	NotStarted
+	// 'Quarantined' the task panicked 'PanicPolicy.MaxPanics' times and is not run again
+	Quarantined
+	// 'Skipped' the task was never run because a task it depends on did not succeed (see 'DAG')
+	Skipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Cancelled:
+		return "cancelled"
+	case NotStarted:
+		return "not started"
+	case Quarantined:
+		return "quarantined"
+	case Skipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// 'Failure' is one failed task, its last error and how many times it was called
+type Failure[T any] struct {
+	Task     T
+	Err      error
+	Attempts int
+}
+
+// 'Report' is the aggregated outcome of the tasks a pool has processed
+// 'Panics' has one record per recovered panic (a task that panicked 3 times has 3 records)
+// 'Latency' is the histogram of 'Result.Latency' for every task that ran ('NotStarted' tasks are left out)
+// 'Skipped' is only counted by a 'DAG', 'Requeued' is how many times a task was put back by an open circuit breaker
+// 'Batches' is only counted by a batch pool ('NewBatch')
+type Report[T any] struct {
+	Succeeded   int
+	Failed      int
+	Cancelled   int
+	NotStarted  int
+	Quarantined int
+	Skipped     int
+	Retries     int
+	Requeued    int
+	Batches     BatchStats
+	Failures    []Failure[T]
+	Panics      []PanicRecord[T]
+	Latency     HistogramSnapshot
+}
@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 'Jitter' spreads out retries so failed tasks do not all retry at the same moment
+type Jitter int
+
+const (
+	// 'NoJitter' sleeps the exact exponential backoff
+	NoJitter Jitter = iota
+	// 'FullJitter' sleeps a random duration between 0 and the backoff
+	FullJitter
+	// 'EqualJitter' sleeps half the backoff plus a random duration up to the other half
+	EqualJitter
+)
+
+// 'RetryPolicy' retries a failed task up to 'MaxAttempts' times (the first call counts as an attempt)
+// the delay before attempt n is 'BaseDelay * 2^(n-1)' capped at 'MaxDelay' and then jittered
+// 'Retryable' classifies errors (nil retries every error not wrapped with 'Permanent')
+// 'Budget' is shared by every worker so a downstream outage does not multiply the load
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      Jitter
+	Retryable   func(err error) bool
+	Budget      *RetryBudget
+}
+
+// 'backoff' is the delay before the retry that follows 'attempt' (1 = the first call failed)
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(r.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if r.MaxDelay > 0 && delay > float64(r.MaxDelay) {
+		delay = float64(r.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	switch r.Jitter {
+	case FullJitter:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	case EqualJitter:
+		half := int64(delay) / 2
+		return time.Duration(half + rand.Int63n(half+1))
+	}
+	return time.Duration(delay)
+}
+
+func (r RetryPolicy) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return true
+}
+
+// 'permanentError' marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// 'Permanent' wraps 'err' so the retry policy gives up on it right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// 'IsPermanent' reports whether 'err' was wrapped with 'Permanent'
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// 'retryBudgetWindow' is how many first attempts' worth of deposits a 'RetryBudget' saves up
+// a long quiet stretch can then pay for at most 'retryBudgetWindow'*'ratio' retries in a burst, not an unbounded number
+const retryBudgetWindow = 100
+
+// 'RetryBudget' caps retries to a ratio of first attempts
+// every first attempt deposits 'ratio' tokens and every retry withdraws 1 token
+// the bucket starts with 'minRetries' tokens and holds at most 'minRetries' + 'retryBudgetWindow'*'ratio' tokens:
+// 'retryBudgetWindow' first attempts' worth of deposits on top of 'minRetries' (30 tokens for 'ratio' 0.2 and 'minRetries' 10)
+// when there is no token left the retry is denied and the task fails with its last error
+type RetryBudget struct {
+	mu        sync.Mutex
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+	denied    int
+}
+
+// 'NewRetryBudget' allows roughly 'ratio' retries per first attempt (0.1 = 10% extra load), plus 'minRetries'
+func NewRetryBudget(ratio float64, minRetries int) *RetryBudget {
+	return &RetryBudget{
+		ratio:     ratio,
+		maxTokens: float64(minRetries) + retryBudgetWindow*ratio,
+		tokens:    float64(minRetries),
+	}
+}
+
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.tokens+b.ratio, b.maxTokens)
+}
+
+// 'withdraw' takes a token, the tolerance lets 10 deposits of 0.1 (0.9999999999999999) pay for a retry
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1-1e-9 {
+		b.denied++
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// 'Denied' is the number of retries refused because the budget was empty
+func (b *RetryBudget) Denied() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.denied
+}
+
+// 'sleep' waits for 'delay' or until 'ctx' is cancelled
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
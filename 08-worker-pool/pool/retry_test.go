@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	// the jittered delays are checked against their bounds over many draws
+	tests := []struct {
+		name     string
+		jitter   Jitter
+		attempt  int
+		min, max time.Duration
+	}{
+		{"the first retry waits 'BaseDelay'", NoJitter, 1, 10 * time.Millisecond, 10 * time.Millisecond},
+		{"the second retry waits twice as long", NoJitter, 2, 20 * time.Millisecond, 20 * time.Millisecond},
+		{"the third retry waits 4 times as long", NoJitter, 3, 40 * time.Millisecond, 40 * time.Millisecond},
+		{"the delay is capped at 'MaxDelay'", NoJitter, 4, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"full jitter is up to the delay", FullJitter, 2, 0, 20 * time.Millisecond},
+		{"equal jitter is at least half the delay", EqualJitter, 2, 10 * time.Millisecond, 20 * time.Millisecond},
+		{"jitter stays under 'MaxDelay'", FullJitter, 10, 0, 50 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Jitter: test.jitter}
+			for i := 0; i < 1000; i++ {
+				if delay := policy.backoff(test.attempt); delay < test.min || delay > test.max {
+					t.Fatalf("got %v, want %v to %v", delay, test.min, test.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	// 2 tokens to start with, each first attempt adds 0.1, at most 2 + 'retryBudgetWindow'*0.1 = 12 tokens
+	b := NewRetryBudget(0.1, 2)
+
+	steps := []struct {
+		deposits    int
+		withdrawals int
+		wantDenied  int
+	}{
+		{0, 3, 1},
+		{10, 2, 2},
+		{1000, 13, 3},
+	}
+	for i, step := range steps {
+		for j := 0; j < step.deposits; j++ {
+			b.deposit()
+		}
+		for j := 0; j < step.withdrawals; j++ {
+			b.withdraw()
+		}
+		if denied := b.Denied(); denied != step.wantDenied {
+			t.Fatalf("step %v: %v retries denied, want %v", i, denied, step.wantDenied)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errDown := errors.New("down")
+
+	tests := []struct {
+		name         string
+		policy       RetryPolicy
+		failures     int
+		err          error
+		wantStatus   Status
+		wantAttempts int
+	}{
+		{"retried until it succeeds", RetryPolicy{MaxAttempts: 5}, 2, errDown, Succeeded, 3},
+		{"failed after 'MaxAttempts'", RetryPolicy{MaxAttempts: 3}, 10, errDown, Failed, 3},
+		{"no retry by default", RetryPolicy{}, 10, errDown, Failed, 1},
+		{"a permanent error is not retried", RetryPolicy{MaxAttempts: 5}, 10, Permanent(errDown), Failed, 1},
+		{"an error 'Retryable' refuses is not retried", RetryPolicy{MaxAttempts: 5, Retryable: func(err error) bool { return false }}, 10, errDown, Failed, 1},
+		{"an empty budget denies the retry", RetryPolicy{MaxAttempts: 5, Budget: NewRetryBudget(0, 1)}, 10, errDown, Failed, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.policy.BaseDelay = time.Millisecond
+			calls := 0
+			p := New(1, func(ctx context.Context, data int) (int, error) {
+				calls++
+				if calls <= test.failures {
+					return 0, test.err
+				}
+				return data, nil
+			}, WithRetry(test.policy))
+			p.Submit(1)
+			p.Close()
+
+			result := <-p.Results()
+			p.Wait()
+			if result.Status != test.wantStatus || result.Attempts != test.wantAttempts {
+				t.Errorf("the task ended %v after %v attempts, want %v after %v", result.Status, result.Attempts, test.wantStatus, test.wantAttempts)
+			}
+			if result.Status == Failed && !errors.Is(result.Err, errDown) {
+				t.Errorf("the task failed with %v, want its last error", result.Err)
+			}
+			if retries := p.Report().Retries; retries != test.wantAttempts-1 {
+				t.Errorf("the report counts %v retries, want %v", retries, test.wantAttempts-1)
+			}
+		})
+	}
+}
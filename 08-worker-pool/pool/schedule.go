@@ -0,0 +1,332 @@
+package pool
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+// a pool runs a task as soon as a worker is free, some tasks should run later:
+// at a time ('SubmitAt'), after a delay ('SubmitAfter') or again and again on a cron schedule ('SubmitCron')
+// a 'Timetable' holds those tasks in a min-heap ordered by their time and 'Submit's each one to the pool when it is due
+// -one goroutine and one timer (armed for the earliest task) serve any number of pending tasks,
+// adding or cancelling one costs O(log n)
+// -tasks due at the same time are submitted in the order they were scheduled
+// -a task submitted late (the pool lanes were full, the clock jumped) is not made up for:
+// a recurring task runs once and is scheduled for its next time after now
+// -the time comes from a 'Clock', tests use a 'FakeClock' (see 'clock.go')
+
+// 'ErrTimetableStopped' is returned when scheduling on a stopped timetable
+var ErrTimetableStopped = errors.New("pool: timetable stopped")
+
+// 'ErrNeverRuns' is returned by 'SubmitCron' for a spec without a next time (e.g. '0 0 30 2 *')
+var ErrNeverRuns = errors.New("pool: cron spec never runs")
+
+// 'ScheduleID' identifies a scheduled task for 'Cancel' and 'Next'
+type ScheduleID uint64
+
+// 'TimetableStats' counts the tasks of a timetable
+// 'Submitted' were handed to the pool, 'Dropped' were due but the pool no longer accepted them
+// 'Cancelled' were cancelled before they were due ('Cancel' or 'Stop'), 'Pending' wait for their time
+type TimetableStats struct {
+	Pending   int
+	Submitted int
+	Dropped   int
+	Cancelled int
+}
+
+// 'scheduledTask' is one entry of the heap, a recurring task stays in the heap with its next time
+type scheduledTask[T any] struct {
+	id       ScheduleID
+	data     T
+	priority Priority
+	at       time.Time
+	// 'seq' orders the tasks due at the same time by when they were scheduled
+	seq   uint64
+	cron  *CronSchedule
+	index int
+}
+
+// 'scheduleHeap' is a 'container/heap' of tasks, the earliest first
+type scheduleHeap[T any] []*scheduledTask[T]
+
+func (h scheduleHeap[T]) Len() int { return len(h) }
+
+func (h scheduleHeap[T]) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h scheduleHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap[T]) Push(x any) {
+	task := x.(*scheduledTask[T])
+	task.index = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *scheduleHeap[T]) Pop() any {
+	old := *h
+	task := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	task.index = -1
+	return task
+}
+
+// 'Timetable' feeds a pool the tasks scheduled for later, 'Stop' it before closing the pool
+type Timetable[T, R any] struct {
+	pool  *Pool[T, R]
+	clock Clock
+
+	mu    sync.Mutex
+	tasks scheduleHeap[T]
+	ids   map[ScheduleID]*scheduledTask[T]
+	next  ScheduleID
+	seq   uint64
+	stats TimetableStats
+
+	// 'wake' tells the goroutine the earliest task may have changed, 'stop' ends it, 'stopped' is closed once it returned
+	wake     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// 'NewTimetable' starts a timetable submitting to 'p' (a nil 'clock' is the 'RealClock')
+func NewTimetable[T, R any](p *Pool[T, R], clock Clock) *Timetable[T, R] {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	t := &Timetable[T, R]{
+		pool:    p,
+		clock:   clock,
+		ids:     make(map[ScheduleID]*scheduledTask[T]),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// 'SubmitAt' submits 'data' to the pool at 'at' (right away if 'at' has passed)
+func (t *Timetable[T, R]) SubmitAt(data T, at time.Time) (ScheduleID, error) {
+	return t.schedule(data, Normal, at, nil)
+}
+
+// 'SubmitAfter' submits 'data' to the pool once 'delay' has passed
+func (t *Timetable[T, R]) SubmitAfter(data T, delay time.Duration) (ScheduleID, error) {
+	return t.schedule(data, Normal, t.clock.Now().Add(delay), nil)
+}
+
+// 'SubmitAtPriority' is 'SubmitAt' to the lane of 'priority'
+func (t *Timetable[T, R]) SubmitAtPriority(data T, at time.Time, priority Priority) (ScheduleID, error) {
+	return t.schedule(data, priority, at, nil)
+}
+
+// 'SubmitCron' submits 'data' to the pool every time the cron spec 'spec' is due (see 'cron.go') until it is cancelled
+func (t *Timetable[T, R]) SubmitCron(data T, spec string) (ScheduleID, error) {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return 0, err
+	}
+	at := cron.Next(t.clock.Now())
+	if at.IsZero() {
+		return 0, ErrNeverRuns
+	}
+	return t.schedule(data, Normal, at, cron)
+}
+
+func (t *Timetable[T, R]) schedule(data T, priority Priority, at time.Time, cron *CronSchedule) (ScheduleID, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.stop:
+		return 0, ErrTimetableStopped
+	default:
+	}
+
+	t.next++
+	t.seq++
+	task := &scheduledTask[T]{id: t.next, data: data, priority: priority, at: at, seq: t.seq, cron: cron}
+	heap.Push(&t.tasks, task)
+	t.ids[task.id] = task
+	if task.index == 0 {
+		t.signal()
+	}
+	return task.id, nil
+}
+
+// 'Cancel' removes a scheduled task (a recurring task does not run again)
+// it returns false when the task is unknown or was already submitted
+func (t *Timetable[T, R]) Cancel(id ScheduleID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, ok := t.ids[id]
+	if !ok {
+		return false
+	}
+	earliest := task.index == 0
+	heap.Remove(&t.tasks, task.index)
+	delete(t.ids, id)
+	t.stats.Cancelled++
+	if earliest {
+		t.signal()
+	}
+	return true
+}
+
+// 'Next' is the time a scheduled task is due, false when it is unknown or was already submitted
+func (t *Timetable[T, R]) Next(id ScheduleID) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, ok := t.ids[id]
+	if !ok {
+		return time.Time{}, false
+	}
+	return task.at, true
+}
+
+// 'Pending' is the number of scheduled tasks waiting for their time
+func (t *Timetable[T, R]) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.tasks)
+}
+
+// 'Stats' returns the counts so far
+func (t *Timetable[T, R]) Stats() TimetableStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats
+	stats.Pending = len(t.tasks)
+	return stats
+}
+
+// 'Stop' cancels every pending task and waits for the goroutine to return, it returns how many were cancelled
+// it is safe to call 'Stop' more than once, a due task being submitted right then is still submitted
+func (t *Timetable[T, R]) Stop() int {
+	cancelled := t.cancelAll()
+	<-t.stopped
+	return cancelled
+}
+
+// 'cancelAll' closes 'stop' (once) and empties the heap, it returns how many tasks were cancelled
+func (t *Timetable[T, R]) cancelAll() int {
+	cancelled := 0
+	t.stopOnce.Do(func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		close(t.stop)
+		cancelled = len(t.tasks)
+		t.stats.Cancelled += cancelled
+		t.tasks = nil
+		clear(t.ids)
+	})
+	return cancelled
+}
+
+// 'signal' wakes the goroutine without blocking, called with 'mu' held
+func (t *Timetable[T, R]) signal() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 'run' submits the due tasks, then sleeps until the earliest pending task is due or the heap changes
+// the wait is computed from 'clock.Now()' each time, a timer armed late fires right away
+func (t *Timetable[T, R]) run() {
+	defer close(t.stopped)
+
+	for {
+		due, wait, pending := t.due()
+		for _, task := range due {
+			err := t.pool.SubmitPriority(task.data, task.priority)
+			t.mu.Lock()
+			if err != nil {
+				t.stats.Dropped++
+			} else {
+				t.stats.Submitted++
+			}
+			t.mu.Unlock()
+		}
+		if len(due) > 0 {
+			// submitting may have taken a while, more tasks may be due
+			continue
+		}
+
+		var timer ClockTimer
+		var fired <-chan time.Time
+		if pending {
+			timer = t.clock.NewTimer(wait)
+			fired = timer.C()
+		}
+		stopped := false
+		select {
+		case <-fired:
+		case <-t.wake:
+		case <-t.stop:
+			stopped = true
+		case <-t.pool.done:
+			// the pool no longer runs anything, the pending tasks are cancelled
+			t.cancelAll()
+			stopped = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// 'due' pops the tasks whose time has come (a recurring task is pushed again with its next time)
+// and returns how long until the earliest remaining one, false when none is pending
+func (t *Timetable[T, R]) due() ([]*scheduledTask[T], time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	var due []*scheduledTask[T]
+	for len(t.tasks) > 0 && !t.tasks[0].at.After(now) {
+		task := t.tasks[0]
+		due = append(due, &scheduledTask[T]{data: task.data, priority: task.priority})
+
+		if task.cron == nil {
+			heap.Pop(&t.tasks)
+			delete(t.ids, task.id)
+			continue
+		}
+		next := task.cron.Next(task.at)
+		if !next.After(now) {
+			// missed runs are skipped
+			next = task.cron.Next(now)
+		}
+		if next.IsZero() {
+			heap.Pop(&t.tasks)
+			delete(t.ids, task.id)
+			continue
+		}
+		t.seq++
+		task.at, task.seq = next, t.seq
+		heap.Fix(&t.tasks, 0)
+	}
+
+	if len(t.tasks) == 0 {
+		return due, 0, false
+	}
+	return due, t.tasks[0].at.Sub(now), true
+}
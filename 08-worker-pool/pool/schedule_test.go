@@ -0,0 +1,314 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// the timetable tests move a 'FakeClock', no test waits for the scheduled time
+
+// 'scheduleStart' is a Sunday (2026-10-18 10:07 UTC)
+var scheduleStart = time.Date(2026, 10, 18, 10, 7, 0, 0, time.UTC)
+
+// 'echo' returns its task
+func echo(ctx context.Context, data int) (int, error) {
+	return data, nil
+}
+
+// 'expectResult' fails unless the next result is 'want'
+func expectResult(t *testing.T, p *Pool[int, int], want int) {
+	t.Helper()
+	select {
+	case result := <-p.Results():
+		if result.Value != want {
+			t.Fatalf("got task %v, want %v", result.Value, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("task %v never ran", want)
+	}
+}
+
+// 'expectNoResult' fails if a task runs within 50ms (real time)
+func expectNoResult(t *testing.T, p *Pool[int, int]) {
+	t.Helper()
+	select {
+	case result := <-p.Results():
+		t.Fatalf("task %v ran before its time", result.Value)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTimetableSubmitAfter(t *testing.T) {
+	clock := NewFakeClock(scheduleStart)
+	p := New(1, echo)
+	timetable := NewTimetable(p, clock)
+	defer p.Close()
+	defer timetable.Stop()
+
+	timetable.SubmitAfter(1, 10*time.Second)
+	timetable.SubmitAfter(2, 5*time.Second)
+	timetable.SubmitAt(3, scheduleStart.Add(5*time.Second))
+
+	clock.WaitForTimers(1)
+	clock.Advance(4 * time.Second)
+	expectNoResult(t, p)
+
+	// tasks due at the same time run in the order they were scheduled
+	clock.Advance(time.Second)
+	expectResult(t, p, 2)
+	expectResult(t, p, 3)
+	expectNoResult(t, p)
+
+	clock.Advance(5 * time.Second)
+	expectResult(t, p, 1)
+
+	// a time in the past is due right away
+	timetable.SubmitAt(4, scheduleStart)
+	expectResult(t, p, 4)
+
+	if stats := timetable.Stats(); stats != (TimetableStats{Submitted: 4}) {
+		t.Errorf("got stats %+v, want 4 submitted", stats)
+	}
+}
+
+func TestTimetableCancel(t *testing.T) {
+	clock := NewFakeClock(scheduleStart)
+	p := New(1, echo)
+	timetable := NewTimetable(p, clock)
+	defer p.Close()
+	defer timetable.Stop()
+
+	first, _ := timetable.SubmitAfter(1, time.Second)
+	second, _ := timetable.SubmitAfter(2, 2*time.Second)
+	timetable.SubmitAfter(3, 3*time.Second)
+
+	// cancelling the earliest task re-arms the timer for the next one
+	if !timetable.Cancel(first) {
+		t.Fatal("cancelling a pending task returned false")
+	}
+	if timetable.Cancel(first) {
+		t.Error("cancelling a task twice returned true")
+	}
+	if timetable.Cancel(ScheduleID(99)) {
+		t.Error("cancelling an unknown task returned true")
+	}
+
+	clock.WaitForTimers(1)
+	clock.Advance(2 * time.Second)
+	expectResult(t, p, 2)
+	if timetable.Cancel(second) {
+		t.Error("cancelling a submitted task returned true")
+	}
+
+	clock.Advance(time.Second)
+	expectResult(t, p, 3)
+	expectNoResult(t, p)
+
+	if stats := timetable.Stats(); stats != (TimetableStats{Submitted: 2, Cancelled: 1}) {
+		t.Errorf("got stats %+v, want 2 submitted and 1 cancelled", stats)
+	}
+}
+
+// 10000 pending tasks at random seconds of the next hour (many at the same second), a third of them cancelled
+// every other task runs exactly once, in time order (scheduling order within a second)
+func TestTimetableManyTimers(t *testing.T) {
+	const numTasks = 10000
+
+	clock := NewFakeClock(scheduleStart)
+	// one worker runs the tasks in the order they were submitted
+	p := New(1, echo)
+	timetable := NewTimetable(p, clock)
+
+	var mu sync.Mutex
+	var ran []int
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for result := range p.Results() {
+			mu.Lock()
+			ran = append(ran, result.Value)
+			mu.Unlock()
+		}
+	}()
+
+	type scheduled struct {
+		task int
+		at   time.Duration
+	}
+	var want []scheduled
+	for i := 0; i < numTasks; i++ {
+		at := time.Duration(1+rand.Intn(3599)) * time.Second
+		id, err := timetable.SubmitAfter(i, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%3 == 0 {
+			timetable.Cancel(id)
+			continue
+		}
+		want = append(want, scheduled{task: i, at: at})
+	}
+	sort.SliceStable(want, func(i, j int) bool { return want[i].at < want[j].at })
+
+	if pending := timetable.Pending(); pending != len(want) {
+		t.Fatalf("got %v pending tasks, want %v", pending, len(want))
+	}
+
+	// advance a minute at a time, the timetable catches up however far behind it is
+	for minute := 0; minute < 60; minute++ {
+		clock.Advance(time.Minute)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for timetable.Stats().Submitted < len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	timetable.Stop()
+	p.Close()
+	<-drained
+	p.Wait()
+
+	if len(ran) != len(want) {
+		t.Fatalf("ran %v tasks, want %v", len(ran), len(want))
+	}
+	for i, task := range ran {
+		if task != want[i].task {
+			t.Fatalf("task number %v is %v, want %v (due at %v)", i, task, want[i].task, want[i].at)
+		}
+	}
+	stats := timetable.Stats()
+	if stats.Cancelled != numTasks-len(want) || stats.Pending != 0 || stats.Dropped != 0 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestTimetableCron(t *testing.T) {
+	clock := NewFakeClock(scheduleStart)
+	p := New(1, echo)
+	timetable := NewTimetable(p, clock)
+	defer p.Close()
+	defer timetable.Stop()
+
+	id, err := timetable.SubmitCron(1, "*/15 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNext := func(want time.Time) {
+		t.Helper()
+		// the next time is set before the task is submitted
+		if next, ok := timetable.Next(id); !ok || !next.Equal(want) {
+			t.Fatalf("next run at %v, want %v", next, want)
+		}
+	}
+	expectNext(time.Date(2026, 10, 18, 10, 15, 0, 0, time.UTC))
+
+	clock.WaitForTimers(1)
+	clock.Advance(8 * time.Minute)
+	expectResult(t, p, 1)
+	expectNext(time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC))
+
+	// an hour passes at once: the 3 missed runs are skipped, the task runs once
+	clock.Advance(time.Hour)
+	expectResult(t, p, 1)
+	expectNoResult(t, p)
+	expectNext(time.Date(2026, 10, 18, 11, 30, 0, 0, time.UTC))
+
+	if !timetable.Cancel(id) {
+		t.Fatal("cancelling a recurring task returned false")
+	}
+	clock.Advance(time.Hour)
+	expectNoResult(t, p)
+
+	if _, err := timetable.SubmitCron(2, "0 0 30 2 *"); !errors.Is(err, ErrNeverRuns) {
+		t.Errorf("got %v for a spec which never runs, want ErrNeverRuns", err)
+	}
+}
+
+func TestTimetableStop(t *testing.T) {
+	clock := NewFakeClock(scheduleStart)
+	p := New(1, echo)
+	timetable := NewTimetable(p, clock)
+
+	timetable.SubmitAfter(1, time.Second)
+	timetable.SubmitCron(2, "@every 1m")
+	if cancelled := timetable.Stop(); cancelled != 2 {
+		t.Errorf("stop cancelled %v tasks, want 2", cancelled)
+	}
+	if cancelled := timetable.Stop(); cancelled != 0 {
+		t.Errorf("a second stop cancelled %v tasks, want 0", cancelled)
+	}
+	if _, err := timetable.SubmitAfter(3, time.Second); !errors.Is(err, ErrTimetableStopped) {
+		t.Errorf("got %v scheduling on a stopped timetable, want ErrTimetableStopped", err)
+	}
+
+	// a pool which stopped cancels the tasks of its timetable
+	timetable = NewTimetable(p, clock)
+	timetable.SubmitAfter(4, time.Second)
+	p.Close()
+	drain(p)
+	p.Wait()
+	<-timetable.stopped
+	if stats := timetable.Stats(); stats.Cancelled != 1 || stats.Pending != 0 {
+		t.Errorf("got stats %+v after the pool stopped, want 1 cancelled", stats)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"0 0 * * *", scheduleStart, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 18, 10, 59, 30, 0, time.UTC), time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", scheduleStart, time.Date(2026, 10, 18, 10, 25, 0, 0, time.UTC)},
+		{"0,30 * * * *", time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC), time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		// Saturday noon: the next weekday 9:00 is Monday
+		{"*/15 9-17 * * mon-fri", time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2026, 10, 19, 17, 45, 0, 0, time.UTC), time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+		// both day fields restricted: the 1st or a Monday
+		{"0 0 1 * mon", scheduleStart, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		// a day field starting with '*' is unrestricted: an odd day and a Monday, the 1st and a Sunday, Tuesday, ...
+		{"0 0 */2 * mon", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * */2", scheduleStart, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", scheduleStart, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"30 4 1,15 * *", time.Date(2026, 10, 15, 4, 30, 0, 0, time.UTC), time.Date(2026, 11, 1, 4, 30, 0, 0, time.UTC)},
+		{"0 0 1 jan *", scheduleStart, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", scheduleStart, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", scheduleStart, scheduleStart.Add(90 * time.Second)},
+		{"0 0 30 2 *", scheduleStart, time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if got := schedule.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%q after %v: got %v, want %v", test.spec, test.from, got, test.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 0 * *",
+		"0 0 * foo *",
+		"@every 10ms",
+		"@every soon",
+		"@sometimes",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("%q: got no error", spec)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"errors"
+)
+
+// a long running pool is usually stopped by a signal (^C is 'SIGINT', 'kill' sends 'SIGTERM')
+// there are 2 ways to stop it:
+// 'Shutdown' drains: no new tasks are accepted, the queued and in-flight tasks finish within a grace period
+// 'Abort' cancels: in-flight tasks see 'ctx.Done()' ('Cancelled') and queued tasks never start ('NotStarted')
+// both need the results to be read, the same as 'Close'
+
+// 'ErrAborted' is returned by 'Wait' after 'Abort' (or after a 'Shutdown' which ran out of grace period)
+var ErrAborted = errors.New("pool: aborted")
+
+// 'Shutdown' stops accepting tasks and waits until every queued task has been processed
+// when 'ctx' is done first (the grace period is over) the pool is aborted and 'Shutdown' returns 'ctx.Err()'
+func (p *Pool[T, R]) Shutdown(ctx context.Context) error {
+	p.Close()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.Abort()
+	<-p.done
+	return ctx.Err()
+}
+
+// 'Abort' stops accepting tasks and cancels the pool, the workers report what was left and return
+// it does not wait for the workers, call 'Wait' (or read 'Results()' until it is closed) for that
+// aborting a pool whose workers already returned does nothing
+func (p *Pool[T, R]) Abort() {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	p.mu.Lock()
+	p.aborted = true
+	p.mu.Unlock()
+
+	p.Close()
+	p.cancel()
+}
+
+// 'Done' is closed once every worker has returned
+func (p *Pool[T, R]) Done() <-chan struct{} {
+	return p.done
+}
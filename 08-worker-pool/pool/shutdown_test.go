@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	// 2 workers run 2 of the 5 tasks, 3 are queued when the pool is stopped
+	tests := []struct {
+		name        string
+		latency     time.Duration
+		stop        func(p *Pool[int, int]) error
+		wantStopErr error
+		wantWaitErr error
+		want        map[Status]int
+	}{
+		{
+			name:    "'Shutdown' drains the queue",
+			latency: 20 * time.Millisecond,
+			stop:    func(p *Pool[int, int]) error { return p.Shutdown(context.Background()) },
+			want:    map[Status]int{Succeeded: 5},
+		},
+		{
+			name:    "'Shutdown' aborts after the grace period",
+			latency: time.Minute,
+			stop: func(p *Pool[int, int]) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+				defer cancel()
+				return p.Shutdown(ctx)
+			},
+			wantStopErr: context.DeadlineExceeded,
+			wantWaitErr: ErrAborted,
+			want:        map[Status]int{Cancelled: 2, NotStarted: 3},
+		},
+		{
+			name:        "'Abort' cancels the tasks in flight and starts no queued one",
+			latency:     time.Minute,
+			stop:        func(p *Pool[int, int]) error { p.Abort(); return nil },
+			wantWaitErr: ErrAborted,
+			want:        map[Status]int{Cancelled: 2, NotStarted: 3},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var running atomic.Int64
+			p := New(2, func(ctx context.Context, data int) (int, error) {
+				running.Add(1)
+				select {
+				case <-time.After(test.latency):
+				case <-ctx.Done():
+					return 0, ctx.Err()
+				}
+				return data, nil
+			})
+
+			counted := make(chan map[Status]int)
+			go func() {
+				counts := make(map[Status]int)
+				for result := range p.Results() {
+					counts[result.Status]++
+				}
+				counted <- counts
+			}()
+			for i := 0; i < 5; i++ {
+				if err := p.Submit(i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			eventually(t, "2 tasks running", func() bool { return running.Load() == 2 })
+
+			if err := test.stop(p); !errors.Is(err, test.wantStopErr) {
+				t.Errorf("stopping returned %v, want %v", err, test.wantStopErr)
+			}
+			if err := p.Submit(5); !errors.Is(err, ErrStopped) {
+				t.Errorf("Submit after the stop returned %v, want ErrStopped", err)
+			}
+			if err := p.Wait(); !errors.Is(err, test.wantWaitErr) {
+				t.Errorf("Wait returned %v, want %v", err, test.wantWaitErr)
+			}
+
+			counts := <-counted
+			for _, status := range []Status{Succeeded, Cancelled, NotStarted} {
+				if counts[status] != test.want[status] {
+					t.Errorf("got %v, want %v", counts, test.want)
+					break
+				}
+			}
+			// aborting a pool which is done does nothing
+			p.Abort()
+			if err := p.Wait(); !errors.Is(err, test.wantWaitErr) {
+				t.Errorf("Wait returned %v after a late 'Abort', want %v", err, test.wantWaitErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package pool
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// with the default scheduler every worker receives from the one shared 'bufferedChannel'
+// for many short tasks the workers spend more time contending on that channel than running tasks
+// the 'WorkStealing' scheduler gives each worker its own local deque instead:
+// -'Submit' spreads tasks round robin over the deques (no single queue every worker waits on)
+// -a worker takes tasks from the front of its own deque
+// -an idle worker steals half of the tasks from the back of another worker's deque
+// -a worker that is removed ('Resize', panic) moves its tasks to a shared 'orphans' deque every worker checks
+// tasks still carry their 'Priority' in the 'Result', but the deques are FIFO, the priority lanes are not used
+
+// 'Scheduler' decides how submitted tasks reach the workers
+type Scheduler int
+
+const (
+	// 'ChannelScheduler' queues tasks in the priority lanes and hands them out on one shared channel (default)
+	ChannelScheduler Scheduler = iota
+	// 'WorkStealing' queues tasks in one deque per worker, idle workers steal from busy ones
+	WorkStealing
+)
+
+func (s Scheduler) String() string {
+	switch s {
+	case ChannelScheduler:
+		return "channel"
+	case WorkStealing:
+		return "work-stealing"
+	}
+	return "unknown"
+}
+
+// 'stealQueueSize' is how many tasks may be queued per worker before 'Submit' blocks
+const stealQueueSize = 64
+
+// 'deque' is a worker's local queue, its owner pops from the front and thieves steal from the back
+// a 'retired' deque belonged to a worker that exited, nothing is pushed to it anymore
+type deque[T any] struct {
+	mu      sync.Mutex
+	items   []job[T]
+	retired bool
+}
+
+// 'push' appends a job, it fails on a retired deque
+func (d *deque[T]) push(j job[T]) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.retired {
+		return false
+	}
+	d.items = append(d.items, j)
+	return true
+}
+
+// 'pop' takes the oldest job
+func (d *deque[T]) pop() (job[T], bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.items) == 0 {
+		var zero job[T]
+		return zero, false
+	}
+	j := d.items[0]
+	d.items[0] = job[T]{}
+	d.items = d.items[1:]
+	return j, true
+}
+
+// 'stealHalf' takes the newer half of the jobs (at least one)
+func (d *deque[T]) stealHalf() []job[T] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.items) - len(d.items)/2
+	if n == 0 {
+		return nil
+	}
+	stolen := append([]job[T](nil), d.items[len(d.items)-n:]...)
+	clear(d.items[len(d.items)-n:])
+	d.items = d.items[:len(d.items)-n]
+	return stolen
+}
+
+// 'retire' marks the deque retired and returns the jobs it still held
+func (d *deque[T]) retire() []job[T] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.retired = true
+	items := d.items
+	d.items = nil
+	return items
+}
+
+// 'stealScheduler' holds the worker deques of a 'WorkStealing' pool
+// 'queued' counts the jobs in every deque, a worker only sleeps (or exits after 'Close') when it is 0
+// sleeping workers wait on 'wake', 'Submit' waits on 'space' while 'queued' is at 'capacity' ('stealQueueSize' per worker, see 'resize')
+// 'parked' is the pool count of tasks put aside by the circuit breaker, after 'Close' workers wait for them too
+type stealScheduler[T any] struct {
+	deques   atomic.Pointer[[]*deque[T]]
+	orphans  deque[T]
+	next     atomic.Uint64
+	queued   atomic.Int64
+	capacity atomic.Int64
+	parked   *atomic.Int64
+
+	// 'mu' guards changes to 'deques' and the sleeping workers, 'stops' are pending 'Resize' shrinks
+	mu     sync.Mutex
+	wake   *sync.Cond
+	idle   atomic.Int64
+	stops  atomic.Int64
+	closed bool
+
+	space   chan struct{}
+	blocked atomic.Int64
+}
+
+func newStealScheduler[T any](numberOfWorkers int, parked *atomic.Int64) *stealScheduler[T] {
+	s := &stealScheduler[T]{
+		parked: parked,
+		space:  make(chan struct{}, 1),
+	}
+	s.capacity.Store(int64(numberOfWorkers) * stealQueueSize)
+	s.wake = sync.NewCond(&s.mu)
+	s.deques.Store(&[]*deque[T]{})
+	return s
+}
+
+// 'register' gives a new worker its deque
+func (s *stealScheduler[T]) register() *deque[T] {
+	d := &deque[T]{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deques := append(append([]*deque[T](nil), *s.deques.Load()...), d)
+	s.deques.Store(&deques)
+	return d
+}
+
+// 'unregister' removes the deque of an exiting worker, its jobs move to 'orphans'
+func (s *stealScheduler[T]) unregister(d *deque[T]) {
+	s.mu.Lock()
+	current := *s.deques.Load()
+	deques := make([]*deque[T], 0, len(current))
+	for _, other := range current {
+		if other != d {
+			deques = append(deques, other)
+		}
+	}
+	s.deques.Store(&deques)
+	s.mu.Unlock()
+
+	for _, j := range d.retire() {
+		s.orphans.push(j)
+	}
+	if s.queued.Load() > 0 {
+		s.signal(true)
+	}
+}
+
+// 'push' queues a job on the next deque round robin, blocking while the scheduler is full
+// it gives up when 'stopped' or 'closing' is closed
+func (s *stealScheduler[T]) push(j job[T], stopped, closing <-chan struct{}) error {
+	for s.queued.Add(1) > s.capacity.Load() {
+		s.queued.Add(-1)
+
+		// counted as blocked before checking again so a worker popping in between sends to 'space'
+		s.blocked.Add(1)
+		if s.queued.Load() < s.capacity.Load() {
+			s.blocked.Add(-1)
+			continue
+		}
+		select {
+		case <-s.space:
+			s.blocked.Add(-1)
+		case <-stopped:
+			s.blocked.Add(-1)
+			return ErrStopped
+		case <-closing:
+			s.blocked.Add(-1)
+			return ErrStopped
+		}
+	}
+
+	for {
+		deques := *s.deques.Load()
+		if len(deques) == 0 {
+			s.orphans.push(j)
+			break
+		}
+		if deques[s.next.Add(1)%uint64(len(deques))].push(j) {
+			break
+		}
+		// the deque was retired between loading and pushing, pick again
+	}
+
+	// after a 'resize' grew the capacity there may be room for the next blocked 'Submit' too
+	if s.blocked.Load() > 0 && s.queued.Load() < s.capacity.Load() {
+		s.wakeSubmit()
+	}
+	s.signal(false)
+	return nil
+}
+
+// 'requeue' queues a task the circuit breaker put aside on the orphans, it never blocks and works after 'close'
+func (s *stealScheduler[T]) requeue(j job[T]) {
+	s.queued.Add(1)
+	s.orphans.push(j)
+	s.signal(false)
+}
+
+// 'take' is the next job for the worker owning 'local': its own deque, the orphans, then a steal
+// it returns false when the worker should exit (a pending 'Resize' shrink, or closed and empty)
+func (s *stealScheduler[T]) take(local *deque[T]) (job[T], bool) {
+	for {
+		if s.takeStop() {
+			var zero job[T]
+			return zero, false
+		}
+
+		if j, ok := local.pop(); ok {
+			return s.taken(j), true
+		}
+		if j, ok := s.orphans.pop(); ok {
+			return s.taken(j), true
+		}
+		if j, ok := s.steal(local); ok {
+			return s.taken(j), true
+		}
+
+		// nothing found: sleep until a push, a shrink, 'Close' or the last parked task coming back
+		// 'idle' is counted before 'queued' is checked so a push in between always wakes this worker
+		s.mu.Lock()
+		s.idle.Add(1)
+		switch {
+		case s.queued.Load() > 0:
+			// a job is on its way between deques (steal or unregister), look again
+			s.idle.Add(-1)
+			s.mu.Unlock()
+			runtime.Gosched()
+			continue
+		case s.closed && s.parked.Load() == 0:
+			s.idle.Add(-1)
+			s.mu.Unlock()
+			var zero job[T]
+			return zero, false
+		case s.stops.Load() == 0:
+			s.wake.Wait()
+		}
+		s.idle.Add(-1)
+		s.mu.Unlock()
+	}
+}
+
+// 'taken' accounts for a job leaving the scheduler and makes room for a blocked 'Submit'
+func (s *stealScheduler[T]) taken(j job[T]) job[T] {
+	s.queued.Add(-1)
+	if s.blocked.Load() > 0 {
+		s.wakeSubmit()
+	}
+	return j
+}
+
+// 'wakeSubmit' lets one blocked 'Submit' check the capacity again
+func (s *stealScheduler[T]) wakeSubmit() {
+	select {
+	case s.space <- struct{}{}:
+	default:
+	}
+}
+
+// 'steal' takes half of the jobs of another deque, starting at a random one
+// the first stolen job is returned, the rest are moved to 'local'
+func (s *stealScheduler[T]) steal(local *deque[T]) (job[T], bool) {
+	deques := *s.deques.Load()
+	if len(deques) > 0 {
+		start := rand.Intn(len(deques))
+		for i := range deques {
+			victim := deques[(start+i)%len(deques)]
+			if victim == local {
+				continue
+			}
+			stolen := victim.stealHalf()
+			if len(stolen) == 0 {
+				continue
+			}
+			for _, j := range stolen[1:] {
+				if !local.push(j) {
+					s.orphans.push(j)
+				}
+			}
+			return stolen[0], true
+		}
+	}
+	var zero job[T]
+	return zero, false
+}
+
+// 'takeStop' claims one pending shrink
+func (s *stealScheduler[T]) takeStop() bool {
+	for {
+		stops := s.stops.Load()
+		if stops <= 0 {
+			return false
+		}
+		if s.stops.CompareAndSwap(stops, stops-1) {
+			return true
+		}
+	}
+}
+
+// 'resize' sets the capacity for 'numberOfWorkers' workers, a larger one lets blocked 'Submit' calls through
+// a smaller one blocks 'Submit' until the queued jobs fit again, none are dropped
+func (s *stealScheduler[T]) resize(numberOfWorkers int) {
+	capacity := int64(numberOfWorkers) * stealQueueSize
+	if s.capacity.Swap(capacity) < capacity && s.blocked.Load() > 0 {
+		s.wakeSubmit()
+	}
+}
+
+// 'stop' makes 'n' workers exit once they finish their current task
+func (s *stealScheduler[T]) stop(n int) {
+	s.stops.Add(int64(n))
+	s.signal(true)
+}
+
+// 'signal' wakes one sleeping worker ('all' wakes every one)
+func (s *stealScheduler[T]) signal(all bool) {
+	if s.idle.Load() == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if all {
+		s.wake.Broadcast()
+	} else {
+		s.wake.Signal()
+	}
+}
+
+// 'close' makes the workers exit once every queued job is taken
+func (s *stealScheduler[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.wake.Broadcast()
+}
+
+// 'depth' is the number of queued jobs
+func (s *stealScheduler[T]) depth() int {
+	return int(s.queued.Load())
+}
@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// the workers added by 'Resize' steal the tasks queued on the first worker's deque,
+// and the larger pool queues 'stealQueueSize' more tasks per worker before 'Submit' blocks
+func TestStealAfterResize(t *testing.T) {
+	const numTasks = 3 + 3*stealQueueSize
+
+	release := make(chan struct{})
+	var running atomic.Int64
+	p := New(1, func(ctx context.Context, data int) (int, error) {
+		running.Add(1)
+		<-release
+		return data, nil
+	}, WithScheduler(WorkStealing))
+
+	var submitted atomic.Int64
+	go func() {
+		defer p.Close()
+		for i := 0; i < numTasks; i++ {
+			p.Submit(i)
+			submitted.Add(1)
+		}
+	}()
+
+	// 1 task running and a full deque of 'stealQueueSize', the next 'Submit' blocks
+	eventually(t, "the first deque full", func() bool { return submitted.Load() == 1+stealQueueSize })
+	time.Sleep(20 * time.Millisecond)
+	if n := submitted.Load(); n != 1+stealQueueSize {
+		t.Fatalf("%v tasks submitted to 1 worker, want %v", n, 1+stealQueueSize)
+	}
+
+	p.Resize(3)
+	eventually(t, "the new workers running stolen tasks", func() bool { return running.Load() == 3 })
+	eventually(t, "every task submitted", func() bool { return submitted.Load() == numTasks })
+	if depth := p.QueueDepth(); depth != 3*stealQueueSize {
+		t.Errorf("%v tasks queued, want %v", depth, 3*stealQueueSize)
+	}
+
+	close(release)
+	results := 0
+	for range p.Results() {
+		results++
+	}
+	p.Wait()
+	if results != numTasks {
+		t.Errorf("got %v results, want %v", results, numTasks)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/alexsmith716/go-concurrency
+
+go 1.21
+
+require golang.org/x/term v0.20.0
+
+require golang.org/x/sys v0.20.0 // indirect